@@ -36,14 +36,26 @@ func main() {
 	}
 
 	spotifyClient := spotify.New(viper.GetString("spotify.authURL"), viper.GetString("spotify.URL"), viper.GetString("spotify.id"), viper.GetString("spotify.secret"))
+	spotifyClient.MaxItems = viper.GetInt("spotify.maxItems")
+	spotifyClient.MaxLibraryItems = viper.GetInt("spotify.maxLibraryItems")
+	spotifyClient.CacheSize = viper.GetInt("spotify.cacheSize")
 
-	router := server.CreateRouter(spotifyClient, logger)
+	router := server.CreateRouter(spotifyClient, logger, viper.GetDuration("timeout"))
 	port := viper.GetString("port")
 
 	if err != nil {
 		glog.Exitf("Error starting the server: %s", err)
 	}
 
+	// The metrics are served apart from the public API, on an address that should only be reachable by the operators
+	if adminAddress := viper.GetString("adminAddress"); adminAddress != "" {
+		go func() {
+			glog.Info("Serving metrics on: ", adminAddress)
+			err := http.ListenAndServe(adminAddress, server.CreateAdminRouter())
+			glog.Exitf("Admin server stopped: %s", err)
+		}()
+	}
+
 	glog.Info("Serving on port: ", port)
 
 	err = http.ListenAndServe(":"+port, router)
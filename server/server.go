@@ -1,7 +1,10 @@
 package server
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
@@ -16,21 +19,30 @@ import (
 
 var logger log.Logger
 var service spotify.Service
+var timeout time.Duration
 
 // CreateRouter is in charge to define all routes
-func CreateRouter(spotifyService spotify.Service, serverLogger log.Logger) http.Handler {
+// Every request is cancelled once the requestTimeout is reached, a zero timeout disables it
+func CreateRouter(spotifyService spotify.Service, serverLogger log.Logger, requestTimeout time.Duration) http.Handler {
 	// Set the logger we will be using in the server
 	logger = serverLogger
 	service = spotifyService
-	r := mux.NewRouter()
+	timeout = requestTimeout
+	// Playlists can be referenced by URL in the path, so it isn't cleaned or https:// would redirect to https:/
+	r := mux.NewRouter().SkipClean(true)
 
-	playlistsHandler := getHandler(playlistsEndpoint())
-	intersectHandler := getHandler(operationEndpoint("intersection"))
-	unionHandler := getHandler(operationEndpoint("union"))
-	profileHandler := getHandler(profileEndpoint())
-	complementHandler := getHandler(operationEndpoint("complement"))
-	userPlaylistsHandler := getHandler(usersEndpoint())
-	playlistHandler := getHandler(playlistEndpoint())
+	playlistsHandler := getHandler(playlistsEndpoint(), transport.DecodePlaylistsRequest)
+	intersectHandler := getHandler(operationEndpoint("intersection"), transport.DecodeOperationRequest)
+	unionHandler := getHandler(operationEndpoint("union"), transport.DecodeOperationRequest)
+	profileHandler := getHandler(profileEndpoint(), transport.DecodeAuthRequest)
+	complementHandler := getHandler(operationEndpoint("complement"), transport.DecodeOperationRequest)
+	differenceHandler := getHandler(operationEndpoint("difference"), transport.DecodeOperationRequest)
+	evaluateHandler := getHandler(evaluateEndpoint(), transport.DecodeEvaluateRequest)
+	quorumHandler := getHandler(quorumEndpoint(), transport.DecodeQuorumRequest)
+	userPlaylistsHandler := getHandler(usersEndpoint(), transport.DecodeUserPlaylistsRequest)
+	playlistHandler := getHandler(playlistEndpoint(), transport.DecodePlaylistRequest)
+	compareHandler := getHandler(compareEndpoint(), transport.DecodeCompareRequest)
+	similarityHandler := getHandler(similarityEndpoint(), transport.DecodeSimilarityRequest)
 
 	// Basic Spotify calls
 	r.Handle("/me", profileHandler).Methods("GET")
@@ -40,8 +52,19 @@ func CreateRouter(spotifyService spotify.Service, serverLogger log.Logger) http.
 	r.Handle("/intersection", intersectHandler).Methods("GET")
 	r.Handle("/union", unionHandler).Methods("GET")
 	r.Handle("/complement", complementHandler).Methods("GET")
-	// Templating endpoints
-	r.Handle("/playlists/{id:[a-zA-Z0-9]+}", playlistHandler).Methods("GET")
+	r.Handle("/difference", differenceHandler).Methods("GET")
+	r.Handle("/evaluate", evaluateHandler).Methods("GET")
+	r.Handle("/quorum", quorumHandler).Methods("GET")
+	r.Handle("/compare", compareHandler).Methods("GET")
+	// Templating endpoints, the playlist can be an ID, URI or URL
+	// The similarity goes first so it isn't taken as the ID of a playlist
+	r.Handle("/playlists/similarity", similarityHandler).Methods("GET")
+	r.Handle("/playlists/{id:.+}", playlistHandler).Methods("GET")
+	// Unknown routes also respond with problem details
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := transport.PopulateRequestContext(req.Context(), req)
+		transport.ErrorEncoder(ctx, &transport.NotFoundError{Message: "Route not found"}, w)
+	})
 	// Health check
 	r.HandleFunc("/healthcheck", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -53,10 +76,24 @@ func CreateRouter(spotifyService spotify.Service, serverLogger log.Logger) http.
 		handlers.AllowedOrigins([]string{"*"}))(r)
 }
 
+// CreateAdminRouter defines the routes that are only served to the operators of the service, like its metrics.
+// It must be served on its own listener, away from the public API and its CORS policy.
+func CreateAdminRouter() http.Handler {
+	r := mux.NewRouter()
+
+	// Only the retries of the requests to Spotify are published, not the rest of the process variables
+	r.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprintf(w, `{"spotify_retries": %s}`, spotify.Retries())
+	}).Methods("GET")
+
+	return r
+}
+
 func playlistEndpoint() endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
-		req := request.(transport.AuthRequest)
-		auth, err := service.Playlist(req.Token, req.PlaylistID)
+		req := request.(transport.PlaylistRequest)
+		auth, err := service.Playlist(ctx, req.Token, req.PlaylistID)
 		if err != nil {
 			return nil, err
 		}
@@ -66,8 +103,8 @@ func playlistEndpoint() endpoint.Endpoint {
 
 func playlistsEndpoint() endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
-		req := request.(transport.AuthRequest)
-		auth, err := service.Playlists(req.Token, req.Offset)
+		req := request.(transport.PlaylistsRequest)
+		auth, err := service.Playlists(ctx, req.Token, req.Offset, req.Limit)
 		if err != nil {
 			return nil, err
 		}
@@ -78,7 +115,7 @@ func playlistsEndpoint() endpoint.Endpoint {
 func profileEndpoint() endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
 		req := request.(transport.AuthRequest)
-		auth, err := service.Profile(req.Token)
+		auth, err := service.Profile(ctx, req.Token)
 		if err != nil {
 			return nil, err
 		}
@@ -88,8 +125,8 @@ func profileEndpoint() endpoint.Endpoint {
 
 func usersEndpoint() endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
-		req := request.(transport.AuthRequest)
-		auth, err := service.UserPlaylists(req.Token, req.Offset, req.Username)
+		req := request.(transport.UserPlaylistsRequest)
+		auth, err := service.UserPlaylists(ctx, req.Token, req.Offset, req.Limit, req.Username)
 		if err != nil {
 			return nil, err
 		}
@@ -99,16 +136,18 @@ func usersEndpoint() endpoint.Endpoint {
 
 func operationEndpoint(operation string) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
-		req := request.(transport.AuthRequest)
+		req := request.(transport.OperationRequest)
 		auth, err := &spotify.NewPlaylistResponse{}, nil
 
 		switch operation {
 		case "intersection":
-			auth, err = service.Intersect(req.Token, req.FirstPlaylist, req.SecondPlaylist, req.Name)
+			auth, err = service.Intersect(ctx, req.Token, req.Playlists, req.Name, options(req.OperationOptions))
 		case "union":
-			auth, err = service.Union(req.Token, req.FirstPlaylist, req.SecondPlaylist, req.Name)
+			auth, err = service.Union(ctx, req.Token, req.Playlists, req.Name, options(req.OperationOptions))
 		case "complement":
-			auth, err = service.Complement(req.Token, req.FirstPlaylist, req.SecondPlaylist, req.Name)
+			auth, err = service.Complement(ctx, req.Token, req.Playlists, req.Name, options(req.OperationOptions))
+		case "difference":
+			auth, err = service.SymmetricDifference(ctx, req.Token, req.Playlists, req.Name, options(req.OperationOptions))
 		}
 		if err != nil {
 			return auth, err
@@ -117,15 +156,103 @@ func operationEndpoint(operation string) endpoint.Endpoint {
 	}
 }
 
-func getHandler(endpoint endpoint.Endpoint) *kithttp.Server {
+func evaluateEndpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(transport.EvaluateRequest)
+		auth, err := service.Evaluate(ctx, req.Token, req.Expression, req.Name, options(req.OperationOptions))
+		if err != nil {
+			return nil, err
+		}
+		return auth, nil
+	}
+}
+
+func quorumEndpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(transport.QuorumRequest)
+		auth, err := service.Quorum(ctx, req.Token, req.Playlists, req.K, req.Name, options(req.OperationOptions))
+		if err != nil {
+			return nil, err
+		}
+		return auth, nil
+	}
+}
+
+func compareEndpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(transport.CompareRequest)
+		auth, err := service.Compare(ctx, req.Token, req.First, req.Second, spotify.Options{Match: req.Match})
+		if err != nil {
+			return nil, err
+		}
+		return auth, nil
+	}
+}
+
+func similarityEndpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		req := request.(transport.SimilarityRequest)
+		auth, err := service.Similarity(ctx, req.Token, req.Top, req.Metric, spotify.Options{Match: req.Match})
+		if err != nil {
+			return nil, err
+		}
+		return auth, nil
+	}
+}
+
+// options gets the options of a set operation from the request
+func options(req transport.OperationOptions) spotify.Options {
+	return spotify.Options{
+		Match:       req.Match,
+		Order:       req.Order,
+		Seed:        req.Seed,
+		Semantics:   req.Semantics,
+		DryRun:      req.DryRun,
+		Target:      req.Target,
+		Mode:        req.Mode,
+		Filters:     req.Filters,
+		Limit:       req.Limit,
+		Sample:      req.Sample,
+		MaxDuration: req.MaxDuration,
+		Granularity: req.Granularity,
+	}
+}
+
+// deadline cancels the calls made by the endpoint to Spotify once the request timeout is reached
+func deadline(next endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+		if timeout <= 0 {
+			return next(ctx, request)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return next(ctx, request)
+	}
+}
+
+// errorHandler logs the errors of the endpoints, requests cancelled by the client are not failures of the service
+type errorHandler struct{}
+
+func (errorHandler) Handle(ctx context.Context, err error) {
+	if errors.Is(err, context.Canceled) {
+		logger.Log("msg", "request cancelled by the client", "request_id", transport.RequestID(ctx))
+		return
+	}
+	logger.Log("err", err, "request_id", transport.RequestID(ctx))
+}
+
+func getHandler(endpoint endpoint.Endpoint, decoder kithttp.DecodeRequestFunc) *kithttp.Server {
 	opts := []kithttp.ServerOption{
-		kithttp.ServerErrorLogger(logger),
-		kithttp.ServerErrorEncoder(transport.IntersectErrorEncoder),
+		kithttp.ServerErrorHandler(errorHandler{}),
+		kithttp.ServerErrorEncoder(transport.ErrorEncoder),
+		kithttp.ServerBefore(transport.PopulateRequestContext),
+		kithttp.ServerAfter(transport.SetRequestIDHeader),
 	}
 
 	return kithttp.NewServer(
-		endpoint,
-		transport.DecodeAuthRequest,
+		deadline(endpoint),
+		decoder,
 		transport.EncodeResponse,
 		opts...)
 }
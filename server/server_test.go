@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/jacobgarcia/settify/spotify"
+)
+
+// slowSpotify never responds, it reports every request that was cancelled by the service
+func slowSpotify(cancelled chan<- bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			cancelled <- true
+		case <-time.After(5 * time.Second):
+			cancelled <- false
+		}
+	}))
+}
+
+func TestMetrics(t *testing.T) {
+	w := httptest.NewRecorder()
+	CreateAdminRouter().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	var metrics map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &metrics); err != nil || w.Code != 200 {
+		t.Fatalf("Expected the metrics as JSON, Got %d %s", w.Code, w.Body.String())
+	}
+	if _, ok := metrics["spotify_retries"]; !ok || len(metrics) != 1 {
+		t.Errorf("Expected only the retries of the requests to Spotify, Got %s", w.Body.String())
+	}
+}
+
+func TestMetricsAreNotPublic(t *testing.T) {
+	router := CreateRouter(nil, log.NewNopLogger(), 0)
+
+	for _, path := range []string{"/debug/vars", "/metrics"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected %d, Got %d for %s", http.StatusNotFound, w.Code, path)
+		}
+	}
+}
+
+func TestDeadlineCancelsSpotify(t *testing.T) {
+	cancelled := make(chan bool, 1)
+	ts := slowSpotify(cancelled)
+	defer ts.Close()
+
+	router := CreateRouter(spotify.New("", ts.URL, "", ""), log.NewNopLogger(), 50*time.Millisecond)
+
+	req := httptest.NewRequest("GET", "/playlists/abc", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected %d, Got %d %s", http.StatusGatewayTimeout, w.Code, w.Body.String())
+	}
+	if !<-cancelled {
+		t.Errorf("Expected the request to Spotify to be cancelled")
+	}
+}
+
+func TestClientDisconnectCancelsSpotify(t *testing.T) {
+	cancelled := make(chan bool, 1)
+	ts := slowSpotify(cancelled)
+	defer ts.Close()
+
+	router := CreateRouter(spotify.New("", ts.URL, "", ""), log.NewNopLogger(), time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	req := httptest.NewRequest("GET", "/playlists/abc", nil).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != 499 || !strings.Contains(w.Body.String(), "/problems/cancelled") {
+		t.Errorf("Expected a cancelled problem, Got %d %s", w.Code, w.Body.String())
+	}
+	if !<-cancelled {
+		t.Errorf("Expected the request to Spotify to be cancelled")
+	}
+}
+
+func TestPlaylistByURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/playlists/37i9dQZF1DXcBWIGoYBM5M" {
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"id": "37i9dQZF1DXcBWIGoYBM5M", "tracks": {"items": []}}`))
+	}))
+	defer ts.Close()
+
+	router := CreateRouter(spotify.New("", ts.URL, "", ""), log.NewNopLogger(), 0)
+
+	// The URL is served as it is, without redirecting clients to a cleaned path
+	cases := []string{
+		"/playlists/https://open.spotify.com/playlist/37i9dQZF1DXcBWIGoYBM5M",
+		"/playlists/https:%2F%2Fopen.spotify.com%2Fplaylist%2F37i9dQZF1DXcBWIGoYBM5M",
+	}
+	for _, path := range cases {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected %d, Got %d %s for %s", http.StatusOK, w.Code, w.Body.String(), path)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package spotify
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultCacheSize is the number of track keys cached when the client doesn't specify one
+const defaultCacheSize = 500000
+
+// trackCache keeps the distinct keys of the tracks of the playlists by their ID, along with the snapshot they were fetched at
+// and how the tracks were matched. A playlist changes its snapshot every time its tracks are modified,
+// so a cached entry is only used while both match.
+// The size of the cache is the number of keys it holds, once it is full the oldest playlists are evicted.
+type trackCache struct {
+	mutex   sync.Mutex
+	order   []string
+	entries map[string]cachedKeys
+	// keys is the number of keys held by all the entries
+	keys int
+}
+
+type cachedKeys struct {
+	snapshot string
+	match    string
+	keys     map[string]bool
+}
+
+func newTrackCache() *trackCache {
+	return &trackCache{
+		order:   []string{},
+		entries: map[string]cachedKeys{},
+	}
+}
+
+// get returns the keys of the tracks of the playlist at the snapshot, a nil cache never has them
+func (t *trackCache) get(id, snapshot, match string) (map[string]bool, bool) {
+	if t == nil || snapshot == "" {
+		return nil, false
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	entry, ok := t.entries[id]
+	if !ok || entry.snapshot != snapshot || entry.match != match {
+		return nil, false
+	}
+	return entry.keys, true
+}
+
+// put stores the keys of the tracks of the playlist at the snapshot, replacing any other entry of the playlist.
+// A playlist with more keys than the size of the cache is not stored.
+func (t *trackCache) put(id, snapshot, match string, keys map[string]bool, size int) {
+	if t == nil || snapshot == "" || len(keys) > size {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if entry, ok := t.entries[id]; ok {
+		t.keys -= len(entry.keys)
+	} else {
+		t.order = append(t.order, id)
+	}
+	t.entries[id] = cachedKeys{snapshot: snapshot, match: match, keys: keys}
+	t.keys += len(keys)
+
+	for t.keys > size {
+		t.keys -= len(t.entries[t.order[0]].keys)
+		delete(t.entries, t.order[0])
+		t.order = t.order[1:]
+	}
+}
+
+// cachedPlaylistKeys retrieves the distinct keys of the tracks of a playlist at its snapshot,
+// using the cache of the client when possible
+func cachedPlaylistKeys(ctx context.Context, token string, playlist Playlist, key identity, match string, c Client) (map[string]bool, error) {
+	if keys, ok := c.cache.get(playlist.ID, playlist.Snapshot, match); ok {
+		return keys, nil
+	}
+
+	tracks, err := getTracks(ctx, token, playlist.ID, c)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]bool{}
+	for _, item := range tracks.Items {
+		keys[key(item.Track)] = true
+	}
+	c.cache.put(playlist.ID, playlist.Snapshot, match, keys, c.cacheSize())
+
+	return keys, nil
+}
+
+// cacheSize is the number of track keys cached
+func (c Client) cacheSize() int {
+	if c.CacheSize > 0 {
+		return c.CacheSize
+	}
+	return defaultCacheSize
+}
@@ -0,0 +1,68 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+const (
+	// chunkSize is the maximum number of tracks Spotify accepts in a single request
+	chunkSize = 100
+	// rollbackTimeout is the deadline to remove a playlist that couldn't be filled
+	rollbackTimeout = 10 * time.Second
+)
+
+// urisOf gets the URIs of the tracks
+func urisOf(tracks []Playlist) []string {
+	uris := make([]string, 0, len(tracks))
+	for _, track := range tracks {
+		uris = append(uris, track.URI)
+	}
+	return uris
+}
+
+// addTracks writes the URIs into a playlist in chunks, one after the other so the order is preserved.
+// When replace is true the first chunk replaces the tracks of the playlist.
+// It returns the snapshot ID after the last chunk and how many tracks were written before any failure.
+func addTracks(ctx context.Context, token, id string, uris []string, replace bool, c Client) (string, int, error) {
+	uri := fmt.Sprintf("v1/playlists/%s/tracks", id)
+	snapshot := ""
+	written := 0
+	for start := 0; start < len(uris); start += chunkSize {
+		end := minimum(start+chunkSize, len(uris))
+		jsonTracks := map[string][]string{
+			"uris": uris[start:end],
+		}
+
+		method := "POST"
+		if replace && start == 0 {
+			method = "PUT"
+		}
+
+		var err error
+		snapshot, err = snapshotRequest(ctx, method, c.URL, uri, token, jsonTracks)
+		if err != nil {
+			return snapshot, written, err
+		}
+		written = end
+	}
+
+	return snapshot, written, nil
+}
+
+// partialError reports that only some of the tracks were written before Spotify failed
+func partialError(err error, written, total int, detail string) error {
+	return &transport.PartialError{
+		Message: fmt.Sprintf("Only %d of %d tracks were added, %s", written, total, detail),
+		Err:     err,
+	}
+}
+
+// removePlaylist unfollows a playlist, which is how Spotify deletes playlists
+func removePlaylist(ctx context.Context, token, id string, c Client) error {
+	_, err := send(ctx, "DELETE", c.URL, fmt.Sprintf("v1/playlists/%s/followers", id), token, nil)
+	return err
+}
@@ -0,0 +1,85 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakePlaylists creates playlists and stores the tracks added to them, failing once failAfter chunks were added
+func fakePlaylists(t *testing.T, failAfter int, added *[]string, removed *bool) *httptest.Server {
+	t.Helper()
+	chunks := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/me":
+			fmt.Fprint(w, `{"id": "user"}`)
+		case r.URL.Path == "/v1/users/user/playlists":
+			fmt.Fprint(w, `{"id": "new"}`)
+		case r.URL.Path == "/v1/playlists/new/followers" && r.Method == "DELETE":
+			*removed = true
+		case r.URL.Path == "/v1/playlists/new/tracks":
+			if chunks == failAfter {
+				w.WriteHeader(http.StatusBadGateway)
+				fmt.Fprint(w, `{"error": {"status": 502, "message": "Bad gateway"}}`)
+				return
+			}
+			chunks++
+
+			var body map[string][]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if len(body["uris"]) > chunkSize {
+				t.Errorf("Expected at most %d tracks per request, Got %d", chunkSize, len(body["uris"]))
+			}
+			*added = append(*added, body["uris"]...)
+			fmt.Fprintf(w, `{"snapshot_id": "snapshot-%d"}`, chunks)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+}
+
+func TestCreatePlaylistChunks(t *testing.T) {
+	added := []string{}
+	removed := false
+	ts := fakePlaylists(t, -1, &added, &removed)
+	defer ts.Close()
+
+	tracks := largePlaylist(0, 250)
+	response, err := createPlaylist(context.Background(), "token", "name", tracksOf(tracks), Client{URL: ts.URL})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if response.Tracks != 250 || response.Snapshot != "snapshot-3" {
+		t.Errorf("Expected 250 tracks and snapshot-3, Got %d and %s", response.Tracks, response.Snapshot)
+	}
+	for index, uri := range added {
+		if uri != tracks.Items[index].Track.URI {
+			t.Fatalf("Expected %s at position %d, Got %s", tracks.Items[index].Track.URI, index, uri)
+		}
+	}
+}
+
+func TestCreatePlaylistRollback(t *testing.T) {
+	added := []string{}
+	removed := false
+	ts := fakePlaylists(t, 2, &added, &removed)
+	defer ts.Close()
+
+	_, err := createPlaylist(context.Background(), "token", "name", tracksOf(largePlaylist(0, 250)), Client{URL: ts.URL})
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+
+	if !removed {
+		t.Errorf("Expected the playlist to be removed")
+	}
+	if !strings.Contains(err.Error(), "Only 200 of 250 tracks were added") {
+		t.Errorf("Expected the error to report the added tracks, Got %s", err)
+	}
+}
@@ -0,0 +1,124 @@
+package spotify
+
+import "context"
+
+// Comparison describes how similar two playlists are, tracks are compared without repetitions
+type Comparison struct {
+	First  string `json:"first"`
+	Second string `json:"second"`
+	// Shared are the tracks in both playlists, FirstOnly and SecondOnly are the tracks in only one of them
+	Shared     Region `json:"shared"`
+	FirstOnly  Region `json:"firstOnly"`
+	SecondOnly Region `json:"secondOnly"`
+	// Jaccard is the size of the intersection divided by the size of the union
+	Jaccard float64 `json:"jaccard"`
+	// Overlap is the size of the intersection divided by the size of the smallest playlist
+	Overlap       float64  `json:"overlap"`
+	SharedArtists []Artist `json:"sharedArtists"`
+}
+
+// Region is a part of the Venn diagram of two playlists, its duration keeps the name Spotify uses for the duration of a track
+type Region struct {
+	Tracks   int `json:"tracks"`
+	Duration int `json:"duration_ms"`
+}
+
+// add counts a track in the region
+func (r *Region) add(track Playlist) {
+	r.Tracks++
+	r.Duration += track.Duration
+}
+
+// compare computes the overlap between two playlists, the shared tracks are the ones of the first playlist
+func compare(first, second PlaylistResponse, key identity) Comparison {
+	firstSet := setOf(first, key)
+	secondSet := setOf(second, key)
+
+	comparison := Comparison{SharedArtists: []Artist{}}
+	for _, id := range firstSet.keys {
+		track := firstSet.tracks[id]
+		if secondSet.contains(track) {
+			comparison.Shared.add(track)
+		} else {
+			comparison.FirstOnly.add(track)
+		}
+	}
+	for _, id := range secondSet.keys {
+		if track := secondSet.tracks[id]; !firstSet.contains(track) {
+			comparison.SecondOnly.add(track)
+		}
+	}
+
+	shared := comparison.Shared.Tracks
+	if union := len(firstSet.keys) + len(secondSet.keys) - shared; union > 0 {
+		comparison.Jaccard = float64(shared) / float64(union)
+	}
+	if smallest := minimum(len(firstSet.keys), len(secondSet.keys)); smallest > 0 {
+		comparison.Overlap = float64(shared) / float64(smallest)
+	}
+
+	// The shared artists keep the order in which they appear in the first playlist
+	secondArtists := artistSet(secondSet)
+	seen := map[string]bool{}
+	for _, id := range firstSet.keys {
+		for _, artist := range firstSet.tracks[id].Artists {
+			artistID := artistKey(artist)
+			if secondArtists[artistID] && !seen[artistID] {
+				seen[artistID] = true
+				comparison.SharedArtists = append(comparison.SharedArtists, artist)
+			}
+		}
+	}
+
+	return comparison
+}
+
+// artistSet gets every artist of the tracks in the set
+func artistSet(set *trackSet) map[string]bool {
+	artists := map[string]bool{}
+	for _, track := range set.tracks {
+		for _, artist := range track.Artists {
+			artists[artistKey(artist)] = true
+		}
+	}
+	return artists
+}
+
+// artistKey identifies an artist by its ID, or by its name when it doesn't have one
+func artistKey(artist Artist) string {
+	if artist.ID == "" {
+		return "name:" + normalize(artist.Name)
+	}
+	return artist.ID
+}
+
+// Compare fetches two playlists and describes how similar they are without creating anything
+func (c Client) Compare(ctx context.Context, token, first, second string, opts Options) (*Comparison, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	key, err := opts.identity()
+	if err != nil {
+		return nil, err
+	}
+
+	sources, err := sourcesOf("playlists", []string{first, second})
+	if err != nil {
+		return nil, err
+	}
+
+	operands := []PlaylistResponse{}
+	for _, source := range sources {
+		tracks, err := source.tracks(ctx, token, c)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, *tracks)
+	}
+
+	comparison := compare(operands[0], operands[1], key)
+	comparison.First = sources[0].String()
+	comparison.Second = sources[1].String()
+
+	return &comparison, nil
+}
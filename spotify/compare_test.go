@@ -0,0 +1,50 @@
+package spotify
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	first := toPlaylistResponse([]Playlist{
+		{ID: "1", Duration: 1000, Artists: []Artist{{ID: "a", Name: "A"}}},
+		{ID: "2", Duration: 2000, Artists: []Artist{{ID: "b", Name: "B"}}},
+		{ID: "3", Duration: 3000, Artists: []Artist{{ID: "c", Name: "C"}}},
+		{ID: "3", Duration: 3000, Artists: []Artist{{ID: "c", Name: "C"}}},
+	})
+	second := toPlaylistResponse([]Playlist{
+		{ID: "3", Duration: 3000, Artists: []Artist{{ID: "c", Name: "C"}}},
+		{ID: "4", Duration: 4000, Artists: []Artist{{ID: "a", Name: "A"}}},
+	})
+
+	comparison := compare(first, second, byID)
+
+	if comparison.Shared != (Region{Tracks: 1, Duration: 3000}) {
+		t.Errorf("Expected %v, Got %v", Region{Tracks: 1, Duration: 3000}, comparison.Shared)
+	}
+	if comparison.FirstOnly != (Region{Tracks: 2, Duration: 3000}) {
+		t.Errorf("Expected %v, Got %v", Region{Tracks: 2, Duration: 3000}, comparison.FirstOnly)
+	}
+	if comparison.SecondOnly != (Region{Tracks: 1, Duration: 4000}) {
+		t.Errorf("Expected %v, Got %v", Region{Tracks: 1, Duration: 4000}, comparison.SecondOnly)
+	}
+	if math.Abs(comparison.Jaccard-0.25) > 1e-9 {
+		t.Errorf("Expected %v, Got %v", 0.25, comparison.Jaccard)
+	}
+	if math.Abs(comparison.Overlap-0.5) > 1e-9 {
+		t.Errorf("Expected %v, Got %v", 0.5, comparison.Overlap)
+	}
+
+	expected := []Artist{{ID: "a", Name: "A"}, {ID: "c", Name: "C"}}
+	if !reflect.DeepEqual(comparison.SharedArtists, expected) {
+		t.Errorf("Expected %v, Got %v", expected, comparison.SharedArtists)
+	}
+}
+
+func TestCompareEmpty(t *testing.T) {
+	comparison := compare(playlistOf(), playlistOf(), byID)
+	if comparison.Jaccard != 0 || comparison.Overlap != 0 || len(comparison.SharedArtists) != 0 {
+		t.Errorf("Expected an empty comparison, Got %+v", comparison)
+	}
+}
@@ -1,37 +1,45 @@
 package spotify
 
-func complement(first PlaylistResponse, second PlaylistResponse) ([]Playlist, error) {
-	// Then we traverse all elements and create the interesection
-	// We doing it this unoptimized way so we can compare it after this O(n*m)
-	complement := []Playlist{}
-	// First we need to calculate the intersection, then remove it from B
-	for _, firstItem := range first.Items {
-		for index, secondItem := range second.Items {
-
-			go func(firstItem Track, secondItem Track, index int) {
-				if firstItem.Track.ID == secondItem.Track.ID {
-					// Remove the element at index i from a.
-					second.Items[index] = second.Items[len(second.Items)-1] // Copy last element to index i.
-					second.Items[len(second.Items)-1] = Track{}             // Erase last element (write zero value).
-					second.Items = second.Items[:len(second.Items)-1]       // Truncate slice.
-				}
-			}(firstItem, secondItem, index)
-		}
+import "context"
+
+func complement(first PlaylistResponse, second PlaylistResponse, opts Options) ([]Playlist, error) {
+	key, err := opts.identity()
+	if err != nil {
+		return nil, err
 	}
 
-	for _, item := range second.Items {
-		playlist := Playlist{
-			ID:   item.Track.ID,
-			Name: item.Track.Name,
-			URI:  item.Track.URI,
+	// Index the first playlist, then keep the tracks of the second one that are not in it
+	// The result keeps the order of the second playlist, with bag semantics every occurrence in the first playlist removes one from the second
+	lookup := setOf(first, key)
+	right := setOf(second, key)
+	complement := newTrackSet(key)
+	for _, k := range right.keys {
+		track := right.tracks[k]
+		if opts.bag() {
+			complement.add(track, right.counts[k]-lookup.count(track))
+		} else if !lookup.contains(track) {
+			complement.add(track, 1)
 		}
-		complement = append(complement, playlist)
 	}
 
-	return complement, nil
+	return complement.items(), nil
+}
+
+// complementAll computes the tracks of every playlist after the first one that are not in the first one
+func complementAll(playlists []PlaylistResponse, opts Options) ([]Playlist, error) {
+	if len(playlists) == 0 {
+		return []Playlist{}, nil
+	}
+
+	rest, err := fold(unify)(playlists[1:], opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return complement(playlists[0], toPlaylistResponse(rest), opts)
 }
 
-// Complement creates a playlist containing all elements that are not in A
-func (c Client) Complement(token, firstPlaylist, secondPlaylist, name string) (*NewPlaylistResponse, error) {
-	return operation(token, firstPlaylist, secondPlaylist, name, c, complement)
+// Complement creates a playlist containing all elements that are not in A, where A is the first playlist of the list
+func (c Client) Complement(ctx context.Context, token string, playlists []string, name string, opts Options) (*NewPlaylistResponse, error) {
+	return operation(ctx, token, playlists, name, opts, c, complementAll)
 }
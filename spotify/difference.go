@@ -0,0 +1,29 @@
+package spotify
+
+import "context"
+
+func symmetricDifference(playlists []PlaylistResponse, opts Options) ([]Playlist, error) {
+	key, err := opts.identity()
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences, sets := occurrencesOf(playlists, key)
+
+	// Keep the tracks that are unique to a single playlist, in the order they were found
+	difference := newTrackSet(key)
+	for _, set := range sets {
+		for _, id := range set.keys {
+			if occurrences.counts[id] == 1 {
+				difference.add(set.tracks[id], opts.multiplicity(set.counts[id]))
+			}
+		}
+	}
+
+	return difference.items(), nil
+}
+
+// SymmetricDifference creates a playlist containing the tracks that are only in one of the playlists
+func (c Client) SymmetricDifference(ctx context.Context, token string, playlists []string, name string, opts Options) (*NewPlaylistResponse, error) {
+	return operation(ctx, token, playlists, name, opts, c, symmetricDifference)
+}
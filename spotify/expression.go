@@ -0,0 +1,426 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+// The expression language composes playlists using set operators. The grammar, from lowest to highest precedence, is:
+//
+//	expression = term { ( "|" | "-" | "^" ) term }
+//	term       = factor { "&" factor }
+//	factor     = "~" factor | "(" expression ")" | function | source
+//	function   = ( "union" | "intersect" | "complement" | "xor" ) "(" expression { "," expression } ")"
+//
+// Where "|" is the union, "&" the intersection, "-" removes the tracks of the right side from the left side,
+// "^" is the symmetric difference and "~" takes every track of the expression that is not in the operand.
+// Playlists can be written as IDs, spotify:playlist URIs or open.spotify.com URLs, and any other source of tracks
+// such as "saved" or "album:ID" can be used as an operand as well.
+// Within the path of a URL "-" is part of the operand, and so is "&" within its query, so they must be followed by a space
+// to be read as operators after a URL.
+
+// token kinds produced by the lexer
+const (
+	tokenEOF = iota
+	tokenSource
+	tokenOperator
+	tokenOpen
+	tokenClose
+	tokenComma
+)
+
+type token struct {
+	kind     int
+	value    string
+	position int
+}
+
+// node is an element of the parsed expression
+type node interface {
+	eval(e evaluation) (PlaylistResponse, error)
+}
+
+// evaluation holds everything needed to evaluate the nodes of an expression
+type evaluation struct {
+	// sources are the tracks of every source in the expression
+	sources map[string]PlaylistResponse
+	// universe are all the tracks in the expression, used by the complement operator
+	universe PlaylistResponse
+	opts     Options
+}
+
+// sourceNode references the tracks of a source
+type sourceNode struct {
+	key string
+}
+
+// unaryNode is the complement of its operand against every track in the expression
+type unaryNode struct {
+	operand node
+}
+
+// binaryNode applies an operator between two expressions
+type binaryNode struct {
+	operator    string
+	left, right node
+}
+
+// functionNode applies a named method over its arguments
+type functionNode struct {
+	name string
+	args []node
+}
+
+// functions are the methods available in the expression language
+var functions = map[string]method{
+	"union":      fold(unify),
+	"intersect":  fold(intersect),
+	"complement": complementAll,
+	"xor":        symmetricDifference,
+}
+
+// Expression is a parsed set expression
+type Expression struct {
+	root    node
+	sources []Source
+}
+
+// ParseExpression parses a set expression such as "(A | B) & ~C" or "union(A, B) - C"
+func ParseExpression(expr string) (*Expression, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{tokens: tokens, seen: map[Source]bool{}}
+	root, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	if next := p.peek(); next.kind != tokenEOF {
+		return nil, parseError(next, "unexpected %q", next.value)
+	}
+
+	return &Expression{root: root, sources: p.sources}, nil
+}
+
+// Sources returns the distinct sources referenced by the expression, in order of appearance
+func (e Expression) Sources() []Source {
+	return e.sources
+}
+
+// method builds the method evaluating the expression, sources must follow the order of Sources
+func (e Expression) method() method {
+	return func(sources []PlaylistResponse, opts Options) ([]Playlist, error) {
+		key, err := opts.identity()
+		if err != nil {
+			return nil, err
+		}
+
+		env := evaluation{
+			sources:  map[string]PlaylistResponse{},
+			universe: universeOf(sources, key),
+			opts:     opts,
+		}
+		for index, source := range e.sources {
+			env.sources[source.String()] = sources[index]
+		}
+
+		result, err := e.root.eval(env)
+		if err != nil {
+			return nil, err
+		}
+
+		return tracksOf(result), nil
+	}
+}
+
+// universeOf creates the union of all the sources without repeating tracks
+func universeOf(sources []PlaylistResponse, key identity) PlaylistResponse {
+	universe := newTrackSet(key)
+	for _, source := range sources {
+		for _, item := range source.Items {
+			if !universe.contains(item.Track) {
+				universe.add(item.Track, 1)
+			}
+		}
+	}
+	return toPlaylistResponse(universe.items())
+}
+
+func (n sourceNode) eval(e evaluation) (PlaylistResponse, error) {
+	return e.sources[n.key], nil
+}
+
+func (n unaryNode) eval(e evaluation) (PlaylistResponse, error) {
+	operand, err := n.operand.eval(e)
+	if err != nil {
+		return PlaylistResponse{}, err
+	}
+
+	result, err := complementAll([]PlaylistResponse{operand, e.universe}, e.opts)
+	if err != nil {
+		return PlaylistResponse{}, err
+	}
+
+	return toPlaylistResponse(result), nil
+}
+
+func (n binaryNode) eval(e evaluation) (PlaylistResponse, error) {
+	left, err := n.left.eval(e)
+	if err != nil {
+		return PlaylistResponse{}, err
+	}
+
+	right, err := n.right.eval(e)
+	if err != nil {
+		return PlaylistResponse{}, err
+	}
+
+	var result []Playlist
+	switch n.operator {
+	case "|":
+		result, err = fold(unify)([]PlaylistResponse{left, right}, e.opts)
+	case "&":
+		result, err = fold(intersect)([]PlaylistResponse{left, right}, e.opts)
+	case "-":
+		// The complement removes the first playlist from the rest
+		result, err = complementAll([]PlaylistResponse{right, left}, e.opts)
+	case "^":
+		result, err = symmetricDifference([]PlaylistResponse{left, right}, e.opts)
+	}
+	if err != nil {
+		return PlaylistResponse{}, err
+	}
+
+	return toPlaylistResponse(result), nil
+}
+
+func (n functionNode) eval(e evaluation) (PlaylistResponse, error) {
+	args := []PlaylistResponse{}
+	for _, arg := range n.args {
+		value, err := arg.eval(e)
+		if err != nil {
+			return PlaylistResponse{}, err
+		}
+		args = append(args, value)
+	}
+
+	result, err := functions[n.name](args, e.opts)
+	if err != nil {
+		return PlaylistResponse{}, err
+	}
+
+	return toPlaylistResponse(result), nil
+}
+
+// isSourceChar reports if the character can be part of a source, like a playlist ID, URI or URL
+func isSourceChar(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	}
+	return strings.IndexByte(":/.?=_%", c) >= 0
+}
+
+// isURLChar reports if the character continues the operand read so far, URLs can also have dashes in their path,
+// like https://open.spotify.com/intl-es/playlist/ID, and ampersands in their query, like ?si=x&pi=y
+func isURLChar(operand string, c byte) bool {
+	switch {
+	case isSourceChar(c):
+		return true
+	case c == '-':
+		return strings.Contains(operand, "/")
+	case c == '&':
+		return strings.Contains(operand, "/") && strings.Contains(operand, "?")
+	}
+	return false
+}
+
+func lex(expr string) ([]token, error) {
+	tokens := []token{}
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.IndexByte("|&-^~", c) >= 0:
+			tokens = append(tokens, token{kind: tokenOperator, value: string(c), position: i})
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenOpen, value: "(", position: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenClose, value: ")", position: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma, value: ",", position: i})
+			i++
+		case isSourceChar(c):
+			start := i
+			for i < len(expr) && isURLChar(expr[start:i], expr[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenSource, value: expr[start:i], position: start})
+		default:
+			return nil, parseError(token{value: string(c), position: i}, "unexpected character %q", string(c))
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF, value: "end of expression", position: len(expr)})
+	return tokens, nil
+}
+
+type parser struct {
+	tokens  []token
+	current int
+	sources []Source
+	seen    map[Source]bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.current]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.current]
+	if t.kind != tokenEOF {
+		p.current++
+	}
+	return t
+}
+
+func (p *parser) expression() (node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		if t.kind != tokenOperator || strings.IndexAny(t.value, "|-^") < 0 {
+			return left, nil
+		}
+		p.next()
+
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{operator: t.value, left: left, right: right}
+	}
+}
+
+func (p *parser) term() (node, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenOperator && p.peek().value == "&" {
+		p.next()
+
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{operator: "&", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) factor() (node, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokenOperator && t.value == "~":
+		operand, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{operand: operand}, nil
+	case t.kind == tokenOpen:
+		inner, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenClose {
+			return nil, parseError(closing, "expected \")\" but found %q", closing.value)
+		}
+		return inner, nil
+	case t.kind == tokenSource && p.peek().kind == tokenOpen:
+		return p.function(t)
+	case t.kind == tokenSource:
+		source, err := expressionSource(t)
+		if err != nil {
+			return nil, err
+		}
+		if !p.seen[source] {
+			p.seen[source] = true
+			p.sources = append(p.sources, source)
+		}
+		return sourceNode{key: source.String()}, nil
+	}
+
+	return nil, parseError(t, "expected a playlist but found %q", t.value)
+}
+
+func (p *parser) function(name token) (node, error) {
+	fn := strings.ToLower(name.value)
+	if _, ok := functions[fn]; !ok {
+		return nil, parseError(name, "unknown function %q", name.value)
+	}
+	// Skip the opening parenthesis
+	p.next()
+
+	args := []node{}
+	for {
+		arg, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		t := p.next()
+		if t.kind == tokenClose {
+			break
+		}
+		if t.kind != tokenComma {
+			return nil, parseError(t, "expected \",\" or \")\" but found %q", t.value)
+		}
+	}
+
+	return functionNode{name: fn, args: args}, nil
+}
+
+// expressionSource gets the source referenced by an operand
+func expressionSource(t token) (Source, error) {
+	source, err := ParseSource(t.value)
+	if err != nil {
+		return Source{}, parseError(t, "invalid source %q", t.value)
+	}
+
+	return source, nil
+}
+
+func parseError(t token, format string, args ...interface{}) error {
+	message := fmt.Sprintf(format, args...)
+	return &transport.ValidationError{
+		Field:   "expr",
+		Message: fmt.Sprintf("invalid expression at position %d: %s", t.position+1, message),
+	}
+}
+
+// Evaluate parses a set expression, evaluates it in memory and saves the result in a new playlist
+func (c Client) Evaluate(ctx context.Context, token, expr, name string, opts Options) (*NewPlaylistResponse, error) {
+	expression, err := ParseExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return execute(ctx, token, expression.Sources(), name, opts, c, expression.method())
+}
@@ -0,0 +1,75 @@
+package spotify
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExpression(t *testing.T) {
+	cases := []struct {
+		expr    string
+		sources []string
+	}{
+		{"(A | B) & ~C", []string{"playlist:A", "playlist:B", "playlist:C"}},
+		{"union(A, B) - C", []string{"playlist:A", "playlist:B", "playlist:C"}},
+		{"spotify:playlist:A ^ https://open.spotify.com/playlist/B?si=x", []string{"playlist:A", "playlist:B"}},
+		{"intersect(A, A, B)", []string{"playlist:A", "playlist:B"}},
+		{"A - saved | spotify:album:B & discography:C", []string{"playlist:A", "saved", "album:B", "discography:C"}},
+		{"https://open.spotify.com/intl-es/playlist/A & B", []string{"playlist:A", "playlist:B"}},
+		{"https://open.spotify.com/playlist/A?si=x&pi=y & B", []string{"playlist:A", "playlist:B"}},
+		{"(https://open.spotify.com/playlist/A?si=x&pi=y)-B", []string{"playlist:A", "playlist:B"}},
+		{"A-B&C", []string{"playlist:A", "playlist:B", "playlist:C"}},
+	}
+
+	for _, c := range cases {
+		expression, err := ParseExpression(c.expr)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %s", c.expr, err)
+			continue
+		}
+		sources := []string{}
+		for _, source := range expression.Sources() {
+			sources = append(sources, source.String())
+		}
+		if !reflect.DeepEqual(sources, c.sources) {
+			t.Errorf("Expected %v, Got %v", c.sources, sources)
+		}
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	cases := []string{"", "A |", "(A | B", "A B", "foo(A)", "A & $", "union(A B)"}
+
+	for _, expr := range cases {
+		if _, err := ParseExpression(expr); err == nil {
+			t.Errorf("Expected an error parsing %q", expr)
+		}
+	}
+}
+
+func TestEvaluateExpression(t *testing.T) {
+	sources := []PlaylistResponse{
+		toPlaylistResponse([]Playlist{{ID: "1"}, {ID: "2"}, {ID: "3"}}),
+		toPlaylistResponse([]Playlist{{ID: "3"}, {ID: "4"}}),
+		toPlaylistResponse([]Playlist{{ID: "2"}, {ID: "4"}}),
+	}
+
+	expression, err := ParseExpression("(A - C) ^ B")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	result, err := expression.method()(sources, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	ids := []string{}
+	for _, track := range result {
+		ids = append(ids, track.ID)
+	}
+	expected := []string{"1", "4"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected %v, Got %v", expected, ids)
+	}
+}
@@ -0,0 +1,323 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+const (
+	// featuresChunkSize is the maximum number of tracks whose audio features Spotify returns in a single request
+	featuresChunkSize = 100
+	// metadataChunkSize is the maximum number of tracks Spotify returns in a single request
+	metadataChunkSize = 50
+)
+
+// AudioFeatures are the acoustic attributes Spotify computes for a track
+type AudioFeatures struct {
+	ID               string  `json:"id"`
+	Tempo            float64 `json:"tempo"`
+	Energy           float64 `json:"energy"`
+	Danceability     float64 `json:"danceability"`
+	Valence          float64 `json:"valence"`
+	Acousticness     float64 `json:"acousticness"`
+	Instrumentalness float64 `json:"instrumentalness"`
+	Liveness         float64 `json:"liveness"`
+	Speechiness      float64 `json:"speechiness"`
+	Loudness         float64 `json:"loudness"`
+}
+
+// FilterReport tells how many tracks of the result a filter removed
+type FilterReport struct {
+	Filter  string `json:"filter"`
+	Removed int    `json:"removed"`
+}
+
+// filterField reads the value a filter compares from a track and its audio features.
+// It reports false when the track doesn't have the value, in which case the track is removed.
+type filterField struct {
+	audio bool
+	value func(track Playlist, features *AudioFeatures) (float64, bool)
+}
+
+// audioField reads one of the audio features of a track
+func audioField(fn func(features *AudioFeatures) float64) filterField {
+	return filterField{
+		audio: true,
+		value: func(track Playlist, features *AudioFeatures) (float64, bool) {
+			if features == nil {
+				return 0, false
+			}
+			return fn(features), true
+		},
+	}
+}
+
+// filterFields are the fields that can be used in a filter, durations are in seconds
+var filterFields = map[string]filterField{
+	"tempo":            audioField(func(f *AudioFeatures) float64 { return f.Tempo }),
+	"energy":           audioField(func(f *AudioFeatures) float64 { return f.Energy }),
+	"danceability":     audioField(func(f *AudioFeatures) float64 { return f.Danceability }),
+	"valence":          audioField(func(f *AudioFeatures) float64 { return f.Valence }),
+	"acousticness":     audioField(func(f *AudioFeatures) float64 { return f.Acousticness }),
+	"instrumentalness": audioField(func(f *AudioFeatures) float64 { return f.Instrumentalness }),
+	"liveness":         audioField(func(f *AudioFeatures) float64 { return f.Liveness }),
+	"speechiness":      audioField(func(f *AudioFeatures) float64 { return f.Speechiness }),
+	"loudness":         audioField(func(f *AudioFeatures) float64 { return f.Loudness }),
+	"popularity": {value: func(track Playlist, features *AudioFeatures) (float64, bool) {
+		return float64(track.Popularity), true
+	}},
+	"duration": {value: func(track Playlist, features *AudioFeatures) (float64, bool) {
+		return float64(track.Duration) / 1000, track.Duration > 0
+	}},
+	"year": {value: func(track Playlist, features *AudioFeatures) (float64, bool) {
+		if track.Album == nil || len(track.Album.ReleaseDate) < 4 {
+			return 0, false
+		}
+		year, err := strconv.Atoi(track.Album.ReleaseDate[:4])
+		return float64(year), err == nil
+	}},
+	"explicit": {value: func(track Playlist, features *AudioFeatures) (float64, bool) {
+		if track.Explicit {
+			return 1, true
+		}
+		return 0, true
+	}},
+}
+
+// operators are the comparisons available in a filter, longer operators go first so they are matched before their prefixes
+var operators = []string{"<=", ">=", "!=", "<", ">", "="}
+
+// Filter is a predicate over the tracks of a result, written as a field, an operator and a value such as "tempo>120",
+// "energy>=0.7", "year<2000", "explicit=false" or "duration<300"
+type Filter struct {
+	Field    string
+	Operator string
+	Value    float64
+	raw      string
+}
+
+// ParseFilter reads a filter
+func ParseFilter(value string) (Filter, error) {
+	value = strings.TrimSpace(value)
+	for _, operator := range operators {
+		index := strings.Index(value, operator)
+		if index < 0 {
+			continue
+		}
+
+		filter := Filter{
+			Field:    strings.ToLower(strings.TrimSpace(value[:index])),
+			Operator: operator,
+			raw:      value,
+		}
+		if _, ok := filterFields[filter.Field]; !ok {
+			return Filter{}, fmt.Errorf("unknown field %q in filter %q", filter.Field, value)
+		}
+
+		operand := strings.TrimSpace(value[index+len(operator):])
+		if filter.Field == "explicit" {
+			explicit, err := strconv.ParseBool(operand)
+			if err != nil || (operator != "=" && operator != "!=") {
+				return Filter{}, fmt.Errorf("filter %q must compare explicit with = or != true or false", value)
+			}
+			if explicit {
+				filter.Value = 1
+			}
+			return filter, nil
+		}
+
+		number, err := strconv.ParseFloat(operand, 64)
+		if err != nil {
+			return Filter{}, fmt.Errorf("filter %q must compare %s with a number", value, filter.Field)
+		}
+		filter.Value = number
+		return filter, nil
+	}
+
+	return Filter{}, fmt.Errorf("filter %q must be a field, an operator and a value like tempo>120", value)
+}
+
+// String writes the filter as it was received
+func (f Filter) String() string {
+	return f.raw
+}
+
+// keeps reports if the track passes the filter
+func (f Filter) keeps(track Playlist, features *AudioFeatures) bool {
+	value, ok := filterFields[f.Field].value(track, features)
+	if !ok {
+		return false
+	}
+
+	switch f.Operator {
+	case "<":
+		return value < f.Value
+	case "<=":
+		return value <= f.Value
+	case ">":
+		return value > f.Value
+	case ">=":
+		return value >= f.Value
+	case "=":
+		return value == f.Value
+	case "!=":
+		return value != f.Value
+	}
+	return false
+}
+
+// filtersOf parses every filter of an operation, reporting all the invalid ones
+func filtersOf(values []string) ([]Filter, error) {
+	filters := []Filter{}
+	invalid := transport.ValidationErrors{}
+	for _, value := range values {
+		filter, err := ParseFilter(value)
+		if err != nil {
+			invalid = append(invalid, &transport.ValidationError{Field: "filter", Message: err.Error()})
+			continue
+		}
+		filters = append(filters, filter)
+	}
+	if len(invalid) > 0 {
+		return nil, invalid
+	}
+
+	return filters, nil
+}
+
+// applyFilters keeps the tracks passing every filter, one filter after the other.
+// Audio features and metadata are only fetched when a filter needs them.
+func applyFilters(ctx context.Context, token string, tracks []Playlist, filters []Filter, c Client) ([]Playlist, []FilterReport, error) {
+	if len(filters) == 0 {
+		return tracks, nil, nil
+	}
+
+	audio := false
+	metadata := false
+	for _, filter := range filters {
+		if filterFields[filter.Field].audio {
+			audio = true
+		} else {
+			metadata = true
+		}
+	}
+
+	// Not every source includes the whole track, like the tracks of an album which miss their popularity
+	var err error
+	if metadata {
+		tracks, err = trackMetadata(ctx, token, tracks, c)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	features := map[string]*AudioFeatures{}
+	if audio {
+		features, err = audioFeatures(ctx, token, tracks, c)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	reports := []FilterReport{}
+	for _, filter := range filters {
+		kept := []Playlist{}
+		for _, track := range tracks {
+			if filter.keeps(track, features[track.ID]) {
+				kept = append(kept, track)
+			}
+		}
+		reports = append(reports, FilterReport{Filter: filter.String(), Removed: len(tracks) - len(kept)})
+		tracks = kept
+	}
+
+	return tracks, reports, nil
+}
+
+// distinctIDs gets the IDs of the tracks without repeating them, local files don't have an ID and are skipped
+func distinctIDs(tracks []Playlist) []string {
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, track := range tracks {
+		if track.ID != "" && !seen[track.ID] {
+			seen[track.ID] = true
+			ids = append(ids, track.ID)
+		}
+	}
+	return ids
+}
+
+// audioFeatures retrieves the audio features of the tracks in batches, by the ID of the track.
+// Spotify doesn't have audio features for every track, those are missing from the result.
+func audioFeatures(ctx context.Context, token string, tracks []Playlist, c Client) (map[string]*AudioFeatures, error) {
+	ids := distinctIDs(tracks)
+	features := map[string]*AudioFeatures{}
+	for start := 0; start < len(ids); start += featuresChunkSize {
+		end := minimum(start+featuresChunkSize, len(ids))
+		path := fmt.Sprintf("v1/audio-features?ids=%s", strings.Join(ids[start:end], ","))
+		body, err := request(ctx, c.URL, path, token, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var response struct {
+			AudioFeatures []*AudioFeatures `json:"audio_features"`
+		}
+		err = json.Unmarshal(body, &response)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, feature := range response.AudioFeatures {
+			if feature != nil {
+				features[feature.ID] = feature
+			}
+		}
+	}
+
+	return features, nil
+}
+
+// trackMetadata retrieves the whole track object of the tracks in batches, keeping the number of sources of each track
+func trackMetadata(ctx context.Context, token string, tracks []Playlist, c Client) ([]Playlist, error) {
+	ids := distinctIDs(tracks)
+	metadata := map[string]Playlist{}
+	for start := 0; start < len(ids); start += metadataChunkSize {
+		end := minimum(start+metadataChunkSize, len(ids))
+		path := fmt.Sprintf("v1/tracks?ids=%s", strings.Join(ids[start:end], ","))
+		body, err := request(ctx, c.URL, path, token, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var response struct {
+			Tracks []*Playlist `json:"tracks"`
+		}
+		err = json.Unmarshal(body, &response)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, track := range response.Tracks {
+			if track != nil {
+				metadata[track.ID] = *track
+			}
+		}
+	}
+
+	complete := make([]Playlist, 0, len(tracks))
+	for _, track := range tracks {
+		if full, ok := metadata[track.ID]; ok {
+			full.Sources = track.Sources
+			track = full
+		}
+		complete = append(complete, track)
+	}
+
+	return complete, nil
+}
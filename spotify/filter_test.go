@@ -0,0 +1,103 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected Filter
+	}{
+		{"tempo>120", Filter{Field: "tempo", Operator: ">", Value: 120, raw: "tempo>120"}},
+		{" Energy >= 0.7 ", Filter{Field: "energy", Operator: ">=", Value: 0.7, raw: "Energy >= 0.7"}},
+		{"year<2000", Filter{Field: "year", Operator: "<", Value: 2000, raw: "year<2000"}},
+		{"explicit=false", Filter{Field: "explicit", Operator: "=", Value: 0, raw: "explicit=false"}},
+		{"explicit!=false", Filter{Field: "explicit", Operator: "!=", Value: 0, raw: "explicit!=false"}},
+		{"duration<=300", Filter{Field: "duration", Operator: "<=", Value: 300, raw: "duration<=300"}},
+	}
+
+	for _, c := range cases {
+		filter, err := ParseFilter(c.value)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %s", c.value, err)
+			continue
+		}
+		if filter != c.expected {
+			t.Errorf("Expected %+v, Got %+v", c.expected, filter)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	cases := []string{"", "tempo", "bpm>120", "tempo>fast", "explicit>true", "explicit=maybe"}
+
+	for _, value := range cases {
+		if _, err := ParseFilter(value); err == nil {
+			t.Errorf("Expected an error parsing %q", value)
+		}
+	}
+}
+
+func TestApplyFilters(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		ids := strings.Split(r.URL.Query().Get("ids"), ",")
+		switch r.URL.Path {
+		case "/v1/audio-features":
+			features := []*AudioFeatures{}
+			for _, id := range ids {
+				// The third track doesn't have audio features
+				if id == "3" {
+					features = append(features, nil)
+					continue
+				}
+				features = append(features, &AudioFeatures{ID: id, Tempo: map[string]float64{"1": 90, "2": 128, "4": 140}[id]})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"audio_features": features})
+		case "/v1/tracks":
+			tracks := []Playlist{}
+			for _, id := range ids {
+				tracks = append(tracks, Playlist{ID: id, Explicit: id == "4", Duration: 200000})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"tracks": tracks})
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	filters, err := filtersOf([]string{"tempo>120", "explicit=false", "duration<300"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	tracks := []Playlist{{ID: "1"}, {ID: "2", Sources: 2}, {ID: "3"}, {ID: "4"}}
+	result, reports, err := applyFilters(context.Background(), "token", tracks, filters, Client{URL: ts.URL})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(result) != 1 || result[0].ID != "2" || result[0].Sources != 2 {
+		t.Errorf("Expected only track 2 with its sources, Got %+v", result)
+	}
+	expected := []FilterReport{{"tempo>120", 2}, {"explicit=false", 1}, {"duration<300", 0}}
+	if !reflect.DeepEqual(reports, expected) {
+		t.Errorf("Expected %v, Got %v", expected, reports)
+	}
+	if requests != 2 {
+		t.Errorf("Expected %d requests, Got %d", 2, requests)
+	}
+
+	// Nothing is fetched without filters
+	if _, _, err = applyFilters(context.Background(), "token", tracks, nil, Client{URL: ts.URL}); err != nil || requests != 2 {
+		t.Errorf("Expected no requests without filters, Got %d and %v", requests-2, err)
+	}
+}
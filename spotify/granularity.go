@@ -0,0 +1,122 @@
+package spotify
+
+import (
+	"fmt"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+// Granularities of the set operations
+const (
+	// GranularityTrack compares tracks, it is the default
+	GranularityTrack = "track"
+	// GranularityArtist compares the primary artists of the tracks
+	GranularityArtist = "artist"
+	// GranularityAlbum compares the albums of the tracks
+	GranularityAlbum = "album"
+)
+
+// granularities are the identities used to group tracks when the operation doesn't compare single tracks
+var granularities = map[string]identity{
+	GranularityArtist: byArtist,
+	GranularityAlbum:  byAlbum,
+}
+
+// Group is an artist or an album in the result of an operation at the artist or album granularity
+type Group struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+	URI  string `json:"uri,omitempty"`
+	// Tracks is the number of tracks of the sources in the group
+	Tracks int `json:"tracks"`
+	// Sources is the number of sources containing the group
+	Sources int `json:"sources,omitempty"`
+}
+
+// byArtist groups the tracks of the same primary artist, tracks without artists fall back to their ID
+func byArtist(track Playlist) string {
+	if len(track.Artists) == 0 {
+		return "id:" + byID(track)
+	}
+	if track.Artists[0].ID == "" {
+		return "name:" + normalize(track.Artists[0].Name)
+	}
+	return "artist:" + track.Artists[0].ID
+}
+
+// byAlbum groups the tracks of the same album, tracks without an album fall back to their ID
+func byAlbum(track Playlist) string {
+	if track.Album == nil || track.Album.ID == "" {
+		return "id:" + byID(track)
+	}
+	return "album:" + track.Album.ID
+}
+
+// grouping gets the identity of the granularity selected in the options, or nil when tracks are compared
+func (o Options) grouping() (identity, error) {
+	if o.Granularity == "" || o.Granularity == GranularityTrack {
+		return nil, nil
+	}
+
+	fn, ok := granularities[o.Granularity]
+	if !ok {
+		return nil, &transport.ValidationError{Field: "granularity", Message: fmt.Sprintf("unknown granularity %q", o.Granularity)}
+	}
+
+	return fn, nil
+}
+
+// expand replaces the groups in the result of an operation with every track of the sources in those groups.
+// A track is only added once, and it takes the number of sources of its group.
+func expand(result []Playlist, sources []PlaylistResponse, group identity, opts Options) ([]Playlist, []Group, error) {
+	key, err := opts.identity()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The groups keep the order of the result, and bags may repeat them
+	groups := []Group{}
+	positions := map[string]int{}
+	for _, track := range result {
+		if _, ok := positions[group(track)]; ok {
+			continue
+		}
+		positions[group(track)] = len(groups)
+		groups = append(groups, groupOf(track, opts.Granularity))
+	}
+
+	expanded := []Playlist{}
+	added := newTrackSet(key)
+	for _, source := range sources {
+		for _, item := range source.Items {
+			position, ok := positions[group(item.Track)]
+			if !ok || added.contains(item.Track) {
+				continue
+			}
+
+			track := item.Track
+			track.Sources = groups[position].Sources
+			added.add(track, 1)
+			expanded = append(expanded, track)
+			groups[position].Tracks++
+		}
+	}
+
+	return expanded, groups, nil
+}
+
+// groupOf describes the group of a track
+func groupOf(track Playlist, granularity string) Group {
+	group := Group{Name: track.Name, Sources: track.Sources}
+	switch {
+	case granularity == GranularityArtist && len(track.Artists) > 0:
+		group.ID = track.Artists[0].ID
+		group.Name = track.Artists[0].Name
+		group.URI = track.Artists[0].URI
+	case granularity == GranularityAlbum && track.Album != nil:
+		group.ID = track.Album.ID
+		group.Name = track.Album.Name
+		group.URI = track.Album.URI
+	}
+	return group
+}
@@ -0,0 +1,89 @@
+package spotify
+
+import (
+	"reflect"
+	"testing"
+)
+
+// byArtists creates a playlist where every track is by the artist with the same index
+func byArtists(ids []string, artists []string) PlaylistResponse {
+	tracks := []Playlist{}
+	for index, id := range ids {
+		tracks = append(tracks, Playlist{
+			ID:      id,
+			Artists: []Artist{{ID: artists[index], Name: "Artist " + artists[index]}},
+			Album:   &Album{ID: "album-" + artists[index], Name: "Album " + artists[index]},
+		})
+	}
+	return toPlaylistResponse(tracks)
+}
+
+func TestArtistGranularity(t *testing.T) {
+	sources := []PlaylistResponse{
+		byArtists([]string{"1", "2", "3"}, []string{"a", "b", "a"}),
+		byArtists([]string{"4", "5", "1"}, []string{"a", "c", "a"}),
+	}
+
+	opts := Options{Granularity: GranularityArtist}
+	group, err := opts.grouping()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	grouped := opts
+	grouped.group = group
+	result, err := fold(intersect)(sources, grouped)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	tracks, groups, err := expand(result, sources, group, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// Every track by the shared artist in any source is in the result, only once
+	expected := []string{"1", "3", "4"}
+	if ids := idsOf(tracks); !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected %v, Got %v", expected, ids)
+	}
+	expectedGroups := []Group{{ID: "a", Name: "Artist a", Tracks: 3}}
+	if !reflect.DeepEqual(groups, expectedGroups) {
+		t.Errorf("Expected %v, Got %v", expectedGroups, groups)
+	}
+}
+
+func TestAlbumGranularity(t *testing.T) {
+	sources := []PlaylistResponse{
+		byArtists([]string{"1", "2"}, []string{"a", "b"}),
+		byArtists([]string{"3"}, []string{"a"}),
+	}
+
+	opts := Options{Granularity: GranularityAlbum}
+	group, _ := opts.grouping()
+	grouped := opts
+	grouped.group = group
+	result, err := complementAll([]PlaylistResponse{sources[1], sources[0]}, grouped)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// The albums of the first playlist that are not in the second one
+	_, groups, err := expand(result, sources, group, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := []Group{{ID: "album-b", Name: "Album b", Tracks: 1}}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Expected %v, Got %v", expected, groups)
+	}
+}
+
+func TestUnknownGranularity(t *testing.T) {
+	if err := (Options{Granularity: "genre"}).validate(); err == nil {
+		t.Errorf("Expected an error with an unknown granularity")
+	}
+	if err := (Options{Granularity: GranularityTrack}).validate(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
@@ -1,160 +1,147 @@
 package spotify
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 
 	"github.com/Pallinder/go-randomdata"
 	"github.com/jacobgarcia/settify/transport"
 )
 
-func operation(token, firstPlaylist, secondPlaylist, name string, c Client, fn method) (*NewPlaylistResponse, error) {
-	// First we need to retrieve the first playlist tracks
-	uri := fmt.Sprintf("%s/v1/playlists/%s/tracks", c.URL, firstPlaylist)
-	req, err := http.NewRequest("GET", uri, bytes.NewBufferString(data.Encode()))
-
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Add("Authorization", token)
-
-	res, err := httpClient.Do(req)
-
-	if err != nil {
-		return nil, err
+func operation(ctx context.Context, token string, playlists []string, name string, opts Options, c Client, fn method) (*NewPlaylistResponse, error) {
+	// Set operations need at least two playlists to work with
+	if len(playlists) < 2 {
+		return nil, &transport.ValidationError{Field: "playlists", Message: "At least two playlists are required"}
 	}
 
-	defer res.Body.Close()
-	response, err := ioutil.ReadAll(res.Body)
-
+	// Playlists can be sent as IDs, URIs or URLs, or be replaced by other sources of tracks
+	sources, err := sourcesOf("playlists", playlists)
 	if err != nil {
 		return nil, err
 	}
 
-	if res.StatusCode != 200 {
-		var errResponse transport.IntersectError
-		err = json.Unmarshal(response, &errResponse)
-
-		if err != nil {
-			return nil, err
-		}
-
-		errResponse = transport.IntersectError{
-			Error: errResponse.Error,
-		}
-
-		resp, err := json.Marshal(errResponse)
+	return execute(ctx, token, sources, name, opts, c, fn)
+}
 
+// execute fetches the tracks of the sources, applies the method over them and saves the result in a new playlist
+func execute(ctx context.Context, token string, sources []Source, name string, opts Options, c Client, fn method) (*NewPlaylistResponse, error) {
+	if opts.Target != "" && opts.Target != TargetLibrary {
+		target, err := idOf("target", KindPlaylist, opts.Target)
 		if err != nil {
 			return nil, err
 		}
-
-		return nil, fmt.Errorf("%s", resp)
-	}
-
-	var trackResponse PlaylistResponse
-	err = json.Unmarshal(response, &trackResponse)
-
-	first := PlaylistResponse{
-		Reference: trackResponse.Reference,
-		Items:     trackResponse.Items,
+		opts.Target = target
 	}
 
+	// Check the filters before making any request to Spotify
+	filters, err := filtersOf(opts.Filters)
 	if err != nil {
 		return nil, err
 	}
 
-	// Now we need to second playlist
-	uri = fmt.Sprintf("%s/v1/playlists/%s/tracks", c.URL, secondPlaylist)
-	req, err = http.NewRequest("GET", uri, bytes.NewBufferString(data.Encode()))
-
+	tracks, groups, err := compute(ctx, token, sources, opts, c, fn)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add("Authorization", token)
-
-	res, err = httpClient.Do(req)
-
+	tracks, reports, err := applyFilters(ctx, token, tracks, filters, c)
 	if err != nil {
 		return nil, err
 	}
+	tracks = trim(tracks, opts)
+
+	// A dry run only previews the result without creating or modifying any playlist
+	if opts.DryRun {
+		preview := NewPlaylistResponse{
+			Name:    name,
+			Tracks:  len(tracks),
+			Items:   summaryOf(tracks),
+			Groups:  groups,
+			Filters: reports,
+		}
+		return &preview, nil
+	}
 
-	defer res.Body.Close()
-	response, err = ioutil.ReadAll(res.Body)
-
+	// Write into the library or an existing playlist when a target was specified, otherwise create a new one
+	var newPlaylistResponse *NewPlaylistResponse
+	switch {
+	case opts.Target == TargetLibrary:
+		newPlaylistResponse, err = writeLibrary(ctx, token, tracks, opts, c)
+	case opts.Target != "":
+		newPlaylistResponse, err = writePlaylist(ctx, token, tracks, opts, c)
+	default:
+		newPlaylistResponse, err = createPlaylist(ctx, token, name, tracks, c)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	if res.StatusCode != 200 {
-		var errResponse transport.IntersectError
-		err = json.Unmarshal(response, &errResponse)
-
-		if err != nil {
-			return nil, err
-		}
+	if opts.report {
+		newPlaylistResponse.Items = summaryOf(tracks)
+	}
+	newPlaylistResponse.Groups = groups
+	newPlaylistResponse.Filters = reports
 
-		errResponse = transport.IntersectError{
-			Error: errResponse.Error,
-		}
+	return newPlaylistResponse, nil
+}
 
-		resp, err := json.Marshal(errResponse)
+// compute fetches the tracks of the sources and applies the method over them.
+// When the operation compares artists or albums, the groups in the result are returned as well.
+func compute(ctx context.Context, token string, sources []Source, opts Options, c Client, fn method) ([]Playlist, []Group, error) {
+	// Check the options before making any request to Spotify
+	if err := opts.validate(); err != nil {
+		return nil, nil, err
+	}
+	group, err := opts.grouping()
+	if err != nil {
+		return nil, nil, err
+	}
 
+	// First we need to retrieve the tracks of every source
+	operands := []PlaylistResponse{}
+	for _, source := range sources {
+		tracks, err := source.tracks(ctx, token, c)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-
-		return nil, fmt.Errorf("%s", resp)
+		operands = append(operands, *tracks)
 	}
 
-	var secondPlaylistResponse PlaylistResponse
-	err = json.Unmarshal(response, &secondPlaylistResponse)
-
+	// Get playlist with applied operation, the tracks stand for their groups when the operation compares groups
+	grouped := opts
+	grouped.group = group
+	op, err := fn(operands, grouped)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	second := PlaylistResponse{
-		Reference: secondPlaylistResponse.Reference,
-		Items:     secondPlaylistResponse.Items,
+	var groups []Group
+	if group != nil {
+		op, groups, err = expand(op, operands, group, opts)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
-	// Get playlist with applied operation
-	op, err := fn(first, second)
+	// Sort the tracks before adding them to the playlist
+	op, err = order(op, operands, opts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if len(op) == 0 {
-		nestedError := transport.NestedError{
-			Status:  204,
-			Message: "Playlists doesn't have anything in common",
-		}
-		errResponse := transport.IntersectError{
-			Error: nestedError,
-		}
-
-		resp, err := json.Marshal(errResponse)
-
-		if err != nil {
-			return nil, err
-		}
-		return nil, fmt.Errorf("%s", resp)
-	}
+	return op, groups, nil
+}
 
-	results := Playlists{
-		Items: op,
-		Total: len(op),
+// createPlaylist creates a new playlist in the account of the current user containing the tracks
+func createPlaylist(ctx context.Context, token, name string, tracks []Playlist, c Client) (*NewPlaylistResponse, error) {
+	if len(tracks) == 0 {
+		return nil, &transport.EmptyResultError{Message: "Playlists doesn't have anything in common"}
 	}
 
 	// Next, we need the user.id of the current session.
 	// This is a requirement to create the new playlist.
-	user, err := userRequest(c.URL, "v1/me", token, nil)
+	user, err := userRequest(ctx, c.URL, "v1/me", token, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -170,89 +157,145 @@ func operation(token, firstPlaylist, secondPlaylist, name string, c Client, fn m
 	}
 
 	fmt.Printf("%+v\n", newPlaylist)
-	uri = fmt.Sprintf("v1/users/%s/playlists", user.ID)
-	playlist, err := userRequest(c.URL, uri, token, newPlaylist)
+	uri := fmt.Sprintf("v1/users/%s/playlists", user.ID)
+	playlist, err := userRequest(ctx, c.URL, uri, token, newPlaylist)
 	if err != nil {
 		return nil, err
 	}
 
 	// Finally, we need to add the tracks to the playlist
-	// Create an slice containing the tracks
-	tracks := []string{}
-	for _, item := range results.Items {
-		tracks = append(tracks, item.URI)
-	}
-	uri = fmt.Sprintf("v1/playlists/%s/tracks", playlist.ID)
-	jsonTracks := map[string][]string{
-		"uris": tracks,
-	}
-	_, err = userRequest(c.URL, uri, token, jsonTracks)
-
+	// If any chunk fails, the half filled playlist is removed
+	uris := urisOf(tracks)
+	snapshot, written, err := addTracks(ctx, token, playlist.ID, uris, false, c)
 	if err != nil {
-		return nil, err
+		// The request may have been cancelled, the rollback gets its own deadline so it still happens
+		rollbackCtx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+		defer cancel()
+
+		detail := "the playlist was removed"
+		if rollbackErr := removePlaylist(rollbackCtx, token, playlist.ID, c); rollbackErr != nil {
+			detail = fmt.Sprintf("the playlist %s could not be removed", playlist.ID)
+		}
+		return nil, partialError(err, written, len(uris), detail)
 	}
 
 	// At the end, we just create a new response object containing the information we need
 	newPlaylistResponse := NewPlaylistResponse{
-		Name:   name,
-		Href:   playlist.ID,
-		Tracks: len(tracks),
+		Name:     name,
+		Href:     playlist.ID,
+		Tracks:   len(uris),
+		Snapshot: snapshot,
 	}
 
-	return &newPlaylistResponse, err
+	return &newPlaylistResponse, nil
 }
 
-func getPlaylists(token, offset, path string, c Client) (*Playlists, error) {
-	uri := fmt.Sprintf("%s/v1/%s/playlists?offset=%s", c.URL, path, offset)
-	req, err := http.NewRequest("GET", uri, bytes.NewBufferString(data.Encode()))
+// getTracks retrieves all the tracks of a playlist
+func getTracks(ctx context.Context, token, id string, c Client) (*PlaylistResponse, error) {
+	return listTracks(ctx, token, fmt.Sprintf("v1/playlists/%s/tracks", id), 100, c)
+}
 
+// listTracks retrieves every page of a listing whose items wrap a track, like the tracks of a playlist or the saved tracks
+func listTracks(ctx context.Context, token, path string, limit int, c Client) (*PlaylistResponse, error) {
+	pages, err := paginate(ctx, token, path, 0, limit, 0, c)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add("Authorization", token)
-
-	res, err := httpClient.Do(req)
-
-	if err != nil {
-		return nil, err
+	tracks := PlaylistResponse{
+		Items: []Track{},
 	}
+	for _, page := range pages {
+		var trackResponse PlaylistResponse
+		err = json.Unmarshal(page, &trackResponse)
+		if err != nil {
+			return nil, err
+		}
 
-	defer res.Body.Close()
-	response, err := ioutil.ReadAll(res.Body)
-
-	if err != nil {
-		return nil, err
+		if tracks.Reference == "" {
+			tracks.Reference = trackResponse.Reference
+		}
+		tracks.Items = append(tracks.Items, trackResponse.Items...)
 	}
 
-	if res.StatusCode != 200 {
-		var errResponse transport.IntersectError
-		err = json.Unmarshal(response, &errResponse)
+	return &tracks, nil
+}
 
-		if err != nil {
-			return nil, err
+// fold turns a binary operation into a method accepting any number of playlists.
+// The operation is applied from left to right, using the result of each step as the first operand of the next one.
+func fold(fn binary) method {
+	return func(playlists []PlaylistResponse, opts Options) ([]Playlist, error) {
+		if len(playlists) == 0 {
+			return []Playlist{}, nil
 		}
 
-		errResponse = transport.IntersectError{
-			Error: errResponse.Error,
+		result := tracksOf(playlists[0])
+		for _, playlist := range playlists[1:] {
+			var err error
+			result, err = fn(toPlaylistResponse(result), playlist, opts)
+			if err != nil {
+				return nil, err
+			}
 		}
 
-		resp, err := json.Marshal(errResponse)
+		return result, nil
+	}
+}
 
-		if err != nil {
-			return nil, err
-		}
+// tracksOf flattens the tracks of a playlist response
+func tracksOf(playlist PlaylistResponse) []Playlist {
+	tracks := make([]Playlist, 0, len(playlist.Items))
+	for _, item := range playlist.Items {
+		tracks = append(tracks, item.Track)
+	}
+	return tracks
+}
 
-		return nil, fmt.Errorf("%s", resp)
+// summaryOf keeps the information needed to identify the resulting tracks of an operation
+func summaryOf(tracks []Playlist) []Playlist {
+	summary := make([]Playlist, 0, len(tracks))
+	for _, track := range tracks {
+		summary = append(summary, Playlist{
+			ID:      track.ID,
+			Name:    track.Name,
+			URI:     track.URI,
+			Artists: track.Artists,
+			Sources: track.Sources,
+		})
 	}
+	return summary
+}
 
-	var playslistsDecoder PlaylistsDecoder
-	err = json.Unmarshal(response, &playslistsDecoder)
+// toPlaylistResponse wraps a list of tracks so it can be used as an operand again
+func toPlaylistResponse(tracks []Playlist) PlaylistResponse {
+	items := []Track{}
+	for _, track := range tracks {
+		items = append(items, Track{Track: track})
+	}
+	return PlaylistResponse{
+		Items: items,
+	}
+}
 
+// getPlaylists lists up to limit playlists starting at offset, a zero limit lists all of them
+func getPlaylists(ctx context.Context, token string, offset, limit int, path string, c Client) (*Playlists, error) {
+	pages, err := paginate(ctx, token, fmt.Sprintf("v1/%s/playlists", path), offset, 50, limit, c)
 	if err != nil {
 		return nil, err
 	}
 
+	var playslistsDecoder PlaylistsDecoder
+	for _, page := range pages {
+		var pageDecoder PlaylistsDecoder
+		err = json.Unmarshal(page, &pageDecoder)
+		if err != nil {
+			return nil, err
+		}
+
+		playslistsDecoder.Items = append(playslistsDecoder.Items, pageDecoder.Items...)
+		playslistsDecoder.Total = pageDecoder.Total
+	}
+
 	var playlists []Playlist
 	for _, playlist := range playslistsDecoder.Items {
 		scope := "public"
@@ -265,12 +308,13 @@ func getPlaylists(token, offset, path string, c Client) (*Playlists, error) {
 			image = playlist.Images[0].URL
 		}
 		newPlaylist := Playlist{
-			ID:     playlist.ID,
-			Name:   playlist.Name,
-			Owner:  playlist.Owner.ID,
-			Tracks: playlist.Tracks.Total,
-			Scope:  scope,
-			Image:  image,
+			ID:       playlist.ID,
+			Name:     playlist.Name,
+			Owner:    playlist.Owner.ID,
+			Tracks:   playlist.Tracks.Total,
+			Scope:    scope,
+			Image:    image,
+			Snapshot: playlist.Snapshot,
 		}
 		playlists = append(playlists, newPlaylist)
 	}
@@ -0,0 +1,72 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+func TestDryRunDoesNotWrite(t *testing.T) {
+	playlists := map[string]PlaylistResponse{
+		"A": toPlaylistResponse([]Playlist{
+			{ID: "1", Name: "One", URI: "spotify:track:1", Artists: []Artist{{ID: "a", Name: "Artist"}}, Popularity: 50},
+			{ID: "2", Name: "Two", URI: "spotify:track:2"},
+		}),
+		"B": playlistOf("1", "3"),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			t.Errorf("Unexpected %s %s in a dry run", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		id := strings.Split(r.URL.Path, "/")[3]
+		json.NewEncoder(w).Encode(playlists[id])
+	}))
+	defer ts.Close()
+
+	for _, target := range []string{"", "C", TargetLibrary} {
+		opts := Options{DryRun: true, Target: target}
+		response, err := Client{URL: ts.URL}.Intersect(context.Background(), "token", []string{"A", "B"}, "Shared", opts)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		// The preview only includes the fields that identify each track
+		expected := []Playlist{{ID: "1", Name: "One", URI: "spotify:track:1", Artists: []Artist{{ID: "a", Name: "Artist"}}}}
+		if response.Name != "Shared" || response.Tracks != 1 || !reflect.DeepEqual(response.Items, expected) {
+			t.Errorf("Expected a preview of %v, Got %+v", expected, response)
+		}
+	}
+}
+
+func TestInvalidPlaylists(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request to %s with invalid playlists", r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	playlists := []string{"37i9dQZF1DXcBWIGoYBM5M", "b!", "https://example.com/playlist/37i9dQZF1DXcBWIGoYBM5M"}
+	_, err := Client{URL: ts.URL}.Intersect(context.Background(), "token", playlists, "Shared", Options{})
+
+	// Every invalid playlist is reported before making any request to Spotify
+	invalid, ok := err.(transport.ValidationErrors)
+	if !ok {
+		t.Fatalf("Expected %T, Got %v", invalid, err)
+	}
+	if len(invalid) != 2 {
+		t.Fatalf("Expected %d, Got %d", 2, len(invalid))
+	}
+	for _, e := range invalid {
+		if e.Field != "playlists" {
+			t.Errorf("Expected %v, Got %v", "playlists", e.Field)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package spotify
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+// Kinds of the Spotify objects an identifier can reference
+const (
+	KindPlaylist = "playlist"
+	KindAlbum    = "album"
+	KindArtist   = "artist"
+	KindTrack    = "track"
+	KindUser     = "user"
+)
+
+var kinds = map[string]bool{
+	KindPlaylist: true,
+	KindAlbum:    true,
+	KindArtist:   true,
+	KindTrack:    true,
+	KindUser:     true,
+}
+
+// idPattern matches the base62 IDs used by Spotify, usernames can also contain dots, dashes and underscores
+var (
+	idPattern       = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+	usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+)
+
+// Identifier references a Spotify object, Kind is empty when it was written as a bare ID
+type Identifier struct {
+	Kind string
+	ID   string
+}
+
+// ParseIdentifier normalizes a bare ID, a Spotify URI such as spotify:playlist:ID or spotify:user:name:playlist:ID,
+// or a URL such as https://open.spotify.com/playlist/ID?si=abc
+func ParseIdentifier(value string) (Identifier, error) {
+	value = strings.TrimSpace(value)
+
+	var identifier Identifier
+	var err error
+	switch {
+	case strings.HasPrefix(value, "spotify:"):
+		identifier, err = parseURI(value)
+	case strings.Contains(value, "/"):
+		identifier, err = parseURL(value)
+	default:
+		identifier = Identifier{ID: value}
+	}
+	if err != nil {
+		return Identifier{}, err
+	}
+
+	pattern := idPattern
+	if identifier.Kind == KindUser {
+		pattern = usernamePattern
+	}
+	if !pattern.MatchString(identifier.ID) {
+		return Identifier{}, fmt.Errorf("%q is not a valid Spotify ID, URI or URL", value)
+	}
+
+	return identifier, nil
+}
+
+// parseURI gets the object referenced by a Spotify URI
+func parseURI(value string) (Identifier, error) {
+	parts := strings.Split(strings.TrimPrefix(value, "spotify:"), ":")
+	switch {
+	// Legacy playlist URIs include their owner, spotify:user:name:playlist:ID
+	case len(parts) == 4 && parts[0] == KindUser && parts[2] == KindPlaylist:
+		return Identifier{Kind: KindPlaylist, ID: parts[3]}, nil
+	case len(parts) == 2 && kinds[parts[0]]:
+		return Identifier{Kind: parts[0], ID: parts[1]}, nil
+	}
+
+	return Identifier{}, fmt.Errorf("%q is not a supported Spotify URI", value)
+}
+
+// parseURL gets the object referenced by a Spotify URL, ignoring its query, locale or embed segments
+func parseURL(value string) (Identifier, error) {
+	if end := strings.IndexAny(value, "?#"); end >= 0 {
+		value = value[:end]
+	}
+
+	segments := []string{}
+	for _, segment := range strings.Split(value, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	// Skip the scheme and look for the host, which must be spotify.com or one of its subdomains
+	if len(segments) > 0 && strings.HasSuffix(segments[0], ":") {
+		segments = segments[1:]
+	}
+	if len(segments) == 0 || !spotifyHost(segments[0]) {
+		return Identifier{}, fmt.Errorf("%q is not a Spotify URL", value)
+	}
+
+	// The last kind in the path wins, so /user/name/playlist/ID references the playlist
+	identifier := Identifier{}
+	for i := 1; i+1 < len(segments); i++ {
+		if kinds[segments[i]] {
+			identifier.Kind = segments[i]
+			identifier.ID = segments[i+1]
+			i++
+		}
+	}
+	if identifier.Kind == "" {
+		return Identifier{}, fmt.Errorf("%q doesn't reference a Spotify object", value)
+	}
+
+	id, err := url.PathUnescape(identifier.ID)
+	if err != nil {
+		return Identifier{}, fmt.Errorf("%q is not a valid Spotify URL", value)
+	}
+	identifier.ID = id
+
+	return identifier, nil
+}
+
+// spotifyHost reports if the host belongs to Spotify, like open.spotify.com
+func spotifyHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "spotify.com" || strings.HasSuffix(host, ".spotify.com")
+}
+
+// idOf parses the value expecting an object of the given kind, reporting the field of the request when it's invalid
+func idOf(field, kind, value string) (string, error) {
+	identifier, err := ParseIdentifier(value)
+	// Usernames are not base62, so a bare one is only checked against the characters they can have
+	if username := strings.TrimSpace(value); err != nil && kind == KindUser && usernamePattern.MatchString(username) {
+		identifier, err = Identifier{Kind: KindUser, ID: username}, nil
+	}
+	if err == nil && identifier.Kind != "" && identifier.Kind != kind {
+		err = fmt.Errorf("expected a %s but %q references a %s", kind, value, identifier.Kind)
+	}
+	if err != nil {
+		return "", &transport.ValidationError{Field: field, Message: err.Error()}
+	}
+
+	return identifier.ID, nil
+}
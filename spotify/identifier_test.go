@@ -0,0 +1,54 @@
+package spotify
+
+import "testing"
+
+func TestParseIdentifier(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected Identifier
+	}{
+		{"37i9dQZF1DXcBWIGoYBM5M", Identifier{ID: "37i9dQZF1DXcBWIGoYBM5M"}},
+		{" 37i9dQZF1DXcBWIGoYBM5M ", Identifier{ID: "37i9dQZF1DXcBWIGoYBM5M"}},
+		{"spotify:playlist:37i9dQZF1DXcBWIGoYBM5M", Identifier{Kind: KindPlaylist, ID: "37i9dQZF1DXcBWIGoYBM5M"}},
+		{"spotify:user:jacob.garcia:playlist:5ZLQ", Identifier{Kind: KindPlaylist, ID: "5ZLQ"}},
+		{"spotify:album:4aawyAB9vmqN3uQ7FjRGTy", Identifier{Kind: KindAlbum, ID: "4aawyAB9vmqN3uQ7FjRGTy"}},
+		{"spotify:artist:0TnOYISbd1XYRBk9myaseg", Identifier{Kind: KindArtist, ID: "0TnOYISbd1XYRBk9myaseg"}},
+		{"spotify:user:jacob.garcia", Identifier{Kind: KindUser, ID: "jacob.garcia"}},
+		{"https://open.spotify.com/playlist/37i9dQZF1DXcBWIGoYBM5M?si=d2f9a1", Identifier{Kind: KindPlaylist, ID: "37i9dQZF1DXcBWIGoYBM5M"}},
+		{"open.spotify.com/intl-es/album/4aawyAB9vmqN3uQ7FjRGTy", Identifier{Kind: KindAlbum, ID: "4aawyAB9vmqN3uQ7FjRGTy"}},
+		{"https://open.spotify.com/user/jacob.garcia/playlist/5ZLQ", Identifier{Kind: KindPlaylist, ID: "5ZLQ"}},
+		{"https://open.spotify.com/embed/artist/0TnOYISbd1XYRBk9myaseg#top", Identifier{Kind: KindArtist, ID: "0TnOYISbd1XYRBk9myaseg"}},
+		{"https://OPEN.SPOTIFY.COM/user/jacob.garcia", Identifier{Kind: KindUser, ID: "jacob.garcia"}},
+		{"https://spotify.com/album/4aawyAB9vmqN3uQ7FjRGTy", Identifier{Kind: KindAlbum, ID: "4aawyAB9vmqN3uQ7FjRGTy"}},
+	}
+
+	for _, c := range cases {
+		identifier, err := ParseIdentifier(c.value)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %s", c.value, err)
+			continue
+		}
+		if identifier != c.expected {
+			t.Errorf("Expected %v, Got %v", c.expected, identifier)
+		}
+	}
+}
+
+func TestParseIdentifierErrors(t *testing.T) {
+	cases := []string{"", "abc!", "spotify:playlist:", "spotify:show:abc", "spotify:user:x:album:y", "https://example.com/playlist/abc", "https://notspotify.com/playlist/abc", "evilspotify.com/playlist/abc", "https://open.spotify.com.evil.com/playlist/abc", "https://open.spotify.com/", "https://open.spotify.com/playlist/a.b"}
+
+	for _, value := range cases {
+		if _, err := ParseIdentifier(value); err == nil {
+			t.Errorf("Expected an error parsing %q", value)
+		}
+	}
+}
+
+func TestIDOf(t *testing.T) {
+	if id, err := idOf("username", KindUser, "jacob.garcia"); err != nil || id != "jacob.garcia" {
+		t.Errorf("Expected %v, Got %v (%v)", "jacob.garcia", id, err)
+	}
+	if _, err := idOf("playlists", KindPlaylist, "spotify:album:4aawyAB9vmqN3uQ7FjRGTy"); err == nil {
+		t.Errorf("Expected an error using an album as a playlist")
+	}
+}
@@ -0,0 +1,78 @@
+package spotify
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// identity computes the key used to decide if two tracks are the same song
+type identity func(track Playlist) string
+
+// identities are the strategies that can be selected with the match option
+var identities = map[string]identity{
+	"id":    byID,
+	"isrc":  byISRC,
+	"title": byTitle,
+}
+
+// byID matches tracks with the exact same Spotify ID, local files don't have an ID so their URI is used instead
+func byID(track Playlist) string {
+	if track.ID == "" {
+		return track.URI
+	}
+	return track.ID
+}
+
+// byISRC matches the same recording released in different albums, singles or compilations.
+// Tracks without an ISRC fall back to their ID.
+func byISRC(track Playlist) string {
+	if track.ExternalIDs == nil || track.ExternalIDs.ISRC == "" {
+		return "id:" + byID(track)
+	}
+	return "isrc:" + strings.ToUpper(track.ExternalIDs.ISRC)
+}
+
+// byTitle matches tracks with the same normalized title and primary artist
+func byTitle(track Playlist) string {
+	artist := ""
+	if len(track.Artists) > 0 {
+		artist = normalize(track.Artists[0].Name)
+	}
+	return normalizeTitle(track.Name) + "|" + artist
+}
+
+// versionPattern finds the parts of a title that describe a version of the song instead of the song itself,
+// such as "(feat. Someone)", "[Remastered 2011]" or "- 2009 Remaster"
+var versionPattern = regexp.MustCompile(`(?i)\s*(\([^)]*\)|\[[^\]]*\]|\s-\s.*$|\s(feat\.?|ft\.|featuring)\s.*$)`)
+
+// versionKeywords are the words that identify a version description
+var versionKeywords = []string{"remaster", "feat", "ft.", "featuring", "version", "with "}
+
+// normalizeTitle removes the version descriptions of a title before normalizing it
+func normalizeTitle(title string) string {
+	title = versionPattern.ReplaceAllStringFunc(title, func(part string) string {
+		lower := strings.ToLower(part)
+		for _, keyword := range versionKeywords {
+			if strings.Contains(lower, keyword) {
+				return ""
+			}
+		}
+		return part
+	})
+	return normalize(title)
+}
+
+// normalize lower cases the text, removes punctuation and collapses spaces
+func normalize(text string) string {
+	text = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+			return unicode.ToLower(r)
+		case unicode.IsSpace(r):
+			return ' '
+		}
+		return -1
+	}, text)
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -1,30 +1,27 @@
 package spotify
 
-func intersect(first PlaylistResponse, second PlaylistResponse) ([]Playlist, error) {
-	// Then we traverse all elements and create the interesection
-	// We doing it this unoptimized way so we can compare it after this O(n*m)
-	intersection := []Playlist{}
-	for _, firstItem := range first.Items {
-		for _, secondItem := range second.Items {
+import "context"
 
-			go func(firstItem Track, secondItem Track) {
-				if firstItem.Track.ID == secondItem.Track.ID {
-					playlist := Playlist{
-						ID:   firstItem.Track.ID,
-						Name: firstItem.Track.Name,
-						URI:  firstItem.Track.URI,
-					}
-					intersection = append(intersection, playlist)
-				}
-			}(firstItem, secondItem)
+func intersect(first PlaylistResponse, second PlaylistResponse, opts Options) ([]Playlist, error) {
+	key, err := opts.identity()
+	if err != nil {
+		return nil, err
+	}
 
-		}
+	// Index both playlists so every lookup is O(1), this makes the intersection O(n+m)
+	// The result keeps the order of the first playlist and, with bag semantics, the minimum count of every track
+	left := setOf(first, key)
+	lookup := setOf(second, key)
+	intersection := newTrackSet(key)
+	for _, k := range left.keys {
+		track := left.tracks[k]
+		intersection.add(track, opts.multiplicity(minimum(left.counts[k], lookup.count(track))))
 	}
 
-	return intersection, nil
+	return intersection.items(), nil
 }
 
-// Intersect is the first method will be implementing in Settify. Basically takes two playlists, and generates a new playlist containing the interesection between them.
-func (c Client) Intersect(token, firstPlaylist, secondPlaylist, name string) (*NewPlaylistResponse, error) {
-	return operation(token, firstPlaylist, secondPlaylist, name, c, intersect)
+// Intersect is the first method will be implementing in Settify. Basically takes a list of playlists, and generates a new playlist containing the interesection between all of them.
+func (c Client) Intersect(ctx context.Context, token string, playlists []string, name string, opts Options) (*NewPlaylistResponse, error) {
+	return operation(ctx, token, playlists, name, opts, c, fold(intersect))
 }
@@ -0,0 +1,68 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+const (
+	// TargetLibrary writes the result into the saved tracks of the user, known as Liked Songs, instead of a playlist
+	TargetLibrary = "library"
+	// libraryChunkSize is the maximum number of tracks Spotify saves or removes from the library in a single request
+	libraryChunkSize = 50
+)
+
+// writeLibrary saves the tracks into the library of the user, or removes them from it when the mode is remove.
+// Saving a track that is already in the library doesn't repeat it, so appending and merging are the same.
+func writeLibrary(ctx context.Context, token string, tracks []Playlist, opts Options, c Client) (*NewPlaylistResponse, error) {
+	// Local files don't have an ID and can't be saved
+	ids := []string{}
+	for _, track := range tracks {
+		if track.ID != "" {
+			ids = append(ids, track.ID)
+		}
+	}
+
+	method, action := "PUT", "saved"
+	if opts.Mode == ModeRemove {
+		method, action = "DELETE", "removed"
+	}
+
+	written, err := libraryRequest(ctx, token, method, ids, c)
+	if err != nil {
+		return nil, &transport.PartialError{
+			Message: fmt.Sprintf("Only %d of %d tracks were %s, the library was left with the changes made so far", written, len(ids), action),
+			Err:     err,
+		}
+	}
+
+	response := NewPlaylistResponse{
+		Name:   "Liked Songs",
+		Href:   TargetLibrary,
+		Tracks: len(ids),
+	}
+
+	return &response, nil
+}
+
+// libraryRequest saves or removes the tracks of the library in chunks,
+// returning how many tracks were modified before any failure
+func libraryRequest(ctx context.Context, token, method string, ids []string, c Client) (int, error) {
+	written := 0
+	for start := 0; start < len(ids); start += libraryChunkSize {
+		end := minimum(start+libraryChunkSize, len(ids))
+		jsonTracks := map[string][]string{
+			"ids": ids[start:end],
+		}
+
+		_, err := send(ctx, method, c.URL, "v1/me/tracks", token, jsonTracks)
+		if err != nil {
+			return written, err
+		}
+		written = end
+	}
+
+	return written, nil
+}
@@ -0,0 +1,87 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+func TestWriteLibraryChunks(t *testing.T) {
+	requests := map[string][]string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/me/tracks" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		var body map[string][]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body["ids"]) > libraryChunkSize {
+			t.Errorf("Expected at most %d tracks per request, Got %d", libraryChunkSize, len(body["ids"]))
+		}
+		requests[r.Method] = append(requests[r.Method], body["ids"]...)
+	}))
+	defer ts.Close()
+
+	tracks := tracksOf(largePlaylist(0, 120))
+	response, err := writeLibrary(context.Background(), "token", tracks, Options{Target: TargetLibrary}, Client{URL: ts.URL})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if response.Tracks != 120 || len(requests["PUT"]) != 120 {
+		t.Errorf("Expected 120 tracks saved, Got %d and %d", response.Tracks, len(requests["PUT"]))
+	}
+
+	_, err = writeLibrary(context.Background(), "token", tracks[:10], Options{Target: TargetLibrary, Mode: ModeRemove}, Client{URL: ts.URL})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(requests["DELETE"]) != 10 {
+		t.Errorf("Expected %d tracks removed, Got %d", 10, len(requests["DELETE"]))
+	}
+}
+
+func TestWriteLibraryPartialFailure(t *testing.T) {
+	chunks := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if chunks == 1 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		chunks++
+	}))
+	defer ts.Close()
+
+	_, err := writeLibrary(context.Background(), "token", tracksOf(largePlaylist(0, 120)), Options{Target: TargetLibrary}, Client{URL: ts.URL})
+	var partial *transport.PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("Expected a partial error, Got %v", err)
+	}
+	expected := "Only 50 of 120 tracks were saved, the library was left with the changes made so far"
+	if partial.Message != expected {
+		t.Errorf("Expected %q, Got %q", expected, partial.Message)
+	}
+}
+
+func TestLibraryModes(t *testing.T) {
+	cases := []struct {
+		opts  Options
+		valid bool
+	}{
+		{Options{Target: TargetLibrary}, true},
+		{Options{Target: TargetLibrary, Mode: ModeRemove}, true},
+		{Options{Target: TargetLibrary, Mode: ModeReplace}, false},
+		{Options{Target: "playlist", Mode: ModeRemove}, false},
+		{Options{Mode: ModeRemove}, false},
+	}
+
+	for _, c := range cases {
+		if err := c.opts.validate(); (err == nil) != c.valid {
+			t.Errorf("Expected valid to be %v for %+v, Got %v", c.valid, c.opts, err)
+		}
+	}
+}
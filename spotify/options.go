@@ -0,0 +1,128 @@
+package spotify
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+// Semantics of the set operations
+const (
+	// SemanticsDistinct treats playlists as sets, every track is in the result at most once
+	SemanticsDistinct = "distinct"
+	// SemanticsBag respects repeated tracks: intersections keep the minimum count, unions add the counts
+	// and complements subtract them
+	SemanticsBag = "bag"
+	// SemanticsBagMax is the same as SemanticsBag except unions keep the maximum count
+	SemanticsBagMax = "bag-max"
+)
+
+// Options customizes how a set operation is computed
+type Options struct {
+	// Match is the identity strategy used to decide if two tracks are the same, see identities
+	Match string
+	// Order is the ordering applied to the resulting tracks, see orderings
+	Order string
+	// Seed makes random orderings and samples reproducible, a random seed is used when it is zero
+	Seed int64
+	// Semantics selects between distinct and bag semantics, distinct is used when it is empty
+	Semantics string
+	// DryRun computes the result without creating or modifying any playlist, the tracks are returned instead
+	DryRun bool
+	// Target is the ID of an existing playlist, or the library of the user, where the result is written instead of creating a new one
+	Target string
+	// Filters are the predicates every track of the result must pass, like "tempo>120" or "explicit=false"
+	Filters []string
+	// Limit is the maximum number of tracks in the result, there is no limit when it is zero
+	Limit int
+	// Sample is the number of random tracks picked from the result, every track is kept when it is zero
+	Sample int
+	// MaxDuration is the maximum total duration of the result, there is no maximum when it is zero
+	MaxDuration time.Duration
+	// Mode is how the result is written into the target, replace is used for playlists and append for the library when it is empty
+	Mode string
+	// Granularity selects if tracks, artists or albums are compared, tracks are compared when it is empty
+	Granularity string
+	// report includes the resulting tracks in the response
+	report bool
+	// group replaces the identity of the tracks while the groups of an operation are computed
+	group identity
+}
+
+// identity gets the identity strategy selected in the options, matching by ID if none was selected
+func (o Options) identity() (identity, error) {
+	if o.group != nil {
+		return o.group, nil
+	}
+	if o.Match == "" {
+		return byID, nil
+	}
+
+	fn, ok := identities[o.Match]
+	if !ok {
+		return nil, &transport.ValidationError{Field: "match", Message: fmt.Sprintf("unknown match strategy %q", o.Match)}
+	}
+
+	return fn, nil
+}
+
+// validate checks the options before any request is made to Spotify
+func (o Options) validate() error {
+	if _, err := o.identity(); err != nil {
+		return err
+	}
+
+	if _, err := o.grouping(); err != nil {
+		return err
+	}
+
+	if _, ok := orderings[o.Order]; o.Order != "" && !ok {
+		return &transport.ValidationError{Field: "order", Message: fmt.Sprintf("unknown order %q", o.Order)}
+	}
+
+	switch o.Mode {
+	case "", ModeReplace, ModeAppend, ModeMerge, ModeRemove:
+	default:
+		return &transport.ValidationError{Field: "mode", Message: fmt.Sprintf("unknown mode %q", o.Mode)}
+	}
+
+	// The library can't be replaced, and only tracks of the library can be removed
+	if o.Target == TargetLibrary && o.Mode == ModeReplace {
+		return &transport.ValidationError{Field: "mode", Message: "the library can't be replaced, use append, merge or remove"}
+	}
+	if o.Target != TargetLibrary && o.Mode == ModeRemove {
+		return &transport.ValidationError{Field: "mode", Message: "tracks can only be removed from the library"}
+	}
+
+	if o.Limit < 0 {
+		return &transport.ValidationError{Field: "limit", Message: "must not be negative"}
+	}
+	if o.Sample < 0 {
+		return &transport.ValidationError{Field: "sample", Message: "must not be negative"}
+	}
+	if o.MaxDuration < 0 {
+		return &transport.ValidationError{Field: "maxDuration", Message: "must not be negative"}
+	}
+
+	switch o.Semantics {
+	case "", SemanticsDistinct, SemanticsBag, SemanticsBagMax:
+	default:
+		return &transport.ValidationError{Field: "semantics", Message: fmt.Sprintf("unknown semantics %q", o.Semantics)}
+	}
+
+	return nil
+}
+
+// bag reports if the operation respects repeated tracks
+func (o Options) bag() bool {
+	return o.Semantics == SemanticsBag || o.Semantics == SemanticsBagMax
+}
+
+// multiplicity gets how many times a track with the given count is added to the result
+func (o Options) multiplicity(count int) int {
+	if count > 0 && !o.bag() {
+		return 1
+	}
+	return count
+}
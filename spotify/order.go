@@ -0,0 +1,153 @@
+package spotify
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+// ordering sorts the tracks resulting from an operation.
+// It receives the source playlists of the operation so tracks can be placed according to their original positions.
+type ordering func(tracks []Playlist, sources []PlaylistResponse, key identity, seed int64) []Playlist
+
+// orderings are the strategies that can be selected with the order option
+var orderings = map[string]ordering{
+	"first":      sourceOrder(0),
+	"second":     sourceOrder(1),
+	"interleave": interleave,
+	"popularity": sortBy(func(a, b Playlist) bool { return a.Popularity > b.Popularity }),
+	"release":    sortBy(func(a, b Playlist) bool { return releaseDate(a) < releaseDate(b) }),
+	"duration":   sortBy(func(a, b Playlist) bool { return a.Duration < b.Duration }),
+	"artist":     sortBy(func(a, b Playlist) bool { return primaryArtist(a) < primaryArtist(b) }),
+	"shuffle":    shuffle,
+}
+
+// order sorts the tracks using the ordering selected in the options, leaving them untouched if none was selected
+func order(tracks []Playlist, sources []PlaylistResponse, opts Options) ([]Playlist, error) {
+	if opts.Order == "" {
+		return tracks, nil
+	}
+
+	fn, ok := orderings[opts.Order]
+	if !ok {
+		return nil, &transport.ValidationError{Field: "order", Message: fmt.Sprintf("unknown order %q", opts.Order)}
+	}
+
+	key, err := opts.identity()
+	if err != nil {
+		return nil, err
+	}
+
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return fn(tracks, sources, key, seed), nil
+}
+
+// sourceOrder keeps the order the tracks have in one of the sources, tracks that are not in it are placed at the end
+func sourceOrder(index int) ordering {
+	return func(tracks []Playlist, sources []PlaylistResponse, key identity, seed int64) []Playlist {
+		if index >= len(sources) {
+			return tracks
+		}
+
+		positions := positionsOf(sources[index], key)
+		return sortBy(func(a, b Playlist) bool {
+			first, ok := positions[key(a)]
+			if !ok {
+				return false
+			}
+			second, ok := positions[key(b)]
+			return !ok || first < second
+		})(tracks, sources, key, seed)
+	}
+}
+
+// interleave takes tracks from every source in turns, each track belongs to the first source containing it
+func interleave(tracks []Playlist, sources []PlaylistResponse, key identity, seed int64) []Playlist {
+	groups := make([][]Playlist, len(sources)+1)
+	positions := []map[string]int{}
+	for _, source := range sources {
+		positions = append(positions, positionsOf(source, key))
+	}
+
+	for _, track := range tracks {
+		group := len(sources)
+		for index := range sources {
+			if _, ok := positions[index][key(track)]; ok {
+				group = index
+				break
+			}
+		}
+		groups[group] = append(groups[group], track)
+	}
+
+	// Keep each group in the order of its source before taking turns
+	for index := range sources {
+		groups[index] = sourceOrder(index)(groups[index], sources, key, seed)
+	}
+
+	interleaved := make([]Playlist, 0, len(tracks))
+	for turn := 0; len(interleaved) < len(tracks); turn++ {
+		for _, group := range groups {
+			if turn < len(group) {
+				interleaved = append(interleaved, group[turn])
+			}
+		}
+	}
+
+	return interleaved
+}
+
+// shuffle randomizes the tracks, the same seed always produces the same order
+func shuffle(tracks []Playlist, sources []PlaylistResponse, key identity, seed int64) []Playlist {
+	shuffled := append([]Playlist{}, tracks...)
+	random := rand.New(rand.NewSource(seed))
+	random.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return shuffled
+}
+
+// sortBy creates an ordering from a comparison, tracks that compare equal keep their order
+func sortBy(less func(a, b Playlist) bool) ordering {
+	return func(tracks []Playlist, sources []PlaylistResponse, key identity, seed int64) []Playlist {
+		sorted := append([]Playlist{}, tracks...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return less(sorted[i], sorted[j])
+		})
+		return sorted
+	}
+}
+
+// positionsOf indexes the first position of every track in a playlist
+func positionsOf(playlist PlaylistResponse, key identity) map[string]int {
+	positions := map[string]int{}
+	for index, item := range playlist.Items {
+		k := key(item.Track)
+		if _, ok := positions[k]; !ok {
+			positions[k] = index
+		}
+	}
+	return positions
+}
+
+func releaseDate(track Playlist) string {
+	if track.Album == nil {
+		return ""
+	}
+	return track.Album.ReleaseDate
+}
+
+func primaryArtist(track Playlist) string {
+	if len(track.Artists) == 0 {
+		return ""
+	}
+	return strings.ToLower(track.Artists[0].Name)
+}
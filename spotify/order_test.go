@@ -0,0 +1,44 @@
+package spotify
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrder(t *testing.T) {
+	first := playlistOf("1", "2", "3")
+	second := playlistOf("3", "4", "2", "5")
+	sources := []PlaylistResponse{first, second}
+	tracks := []Playlist{{ID: "1"}, {ID: "2"}, {ID: "3"}, {ID: "4"}, {ID: "5"}}
+
+	cases := []struct {
+		order    string
+		expected []string
+	}{
+		{"", []string{"1", "2", "3", "4", "5"}},
+		{"first", []string{"1", "2", "3", "4", "5"}},
+		{"second", []string{"3", "4", "2", "5", "1"}},
+		{"interleave", []string{"1", "4", "2", "5", "3"}},
+	}
+
+	for _, c := range cases {
+		result, err := order(tracks, sources, Options{Order: c.order})
+		if err != nil {
+			t.Errorf("Unexpected error with order %q: %s", c.order, err)
+			continue
+		}
+		if !reflect.DeepEqual(idsOf(result), c.expected) {
+			t.Errorf("%s: Expected %v, Got %v", c.order, c.expected, idsOf(result))
+		}
+	}
+
+	shuffled, _ := order(tracks, sources, Options{Order: "shuffle", Seed: 42})
+	again, _ := order(tracks, sources, Options{Order: "shuffle", Seed: 42})
+	if !reflect.DeepEqual(shuffled, again) {
+		t.Errorf("Expected the same order with the same seed, Got %v and %v", idsOf(shuffled), idsOf(again))
+	}
+
+	if _, err := order(tracks, sources, Options{Order: "unknown"}); err == nil {
+		t.Errorf("Expected an error with an unknown order")
+	}
+}
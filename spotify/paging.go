@@ -0,0 +1,126 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+const (
+	// defaultMaxItems is the upper bound of items retrieved from a listing when the client doesn't specify one
+	defaultMaxItems = 10000
+	// defaultMaxLibraryItems is the upper bound of the saved tracks retrieved when the client doesn't specify one,
+	// Spotify doesn't limit the size of the library so it is much higher than the one of other listings
+	defaultMaxLibraryItems = 100000
+	// pageWorkers is the number of pages fetched at the same time
+	pageWorkers = 4
+)
+
+// Page is the paging object Spotify wraps every listing with
+type Page struct {
+	Next   string `json:"next"`
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+	Total  int    `json:"total"`
+}
+
+// paginate retrieves every page of a listing starting at offset, returning the body of each page in order.
+// The first page tells how many items there are, then the rest of the pages are fetched concurrently.
+// No more than upTo items are retrieved, or the MaxItems of the client when upTo is zero or greater.
+// A listing that doesn't fit in the MaxItems of the client is an error, so results are never computed over part of it.
+func paginate(ctx context.Context, token, path string, offset, limit, upTo int, c Client) ([][]byte, error) {
+	bounded := upTo <= 0 || upTo > c.maxItems()
+	if bounded {
+		upTo = c.maxItems()
+	}
+	limit = minimum(limit, upTo)
+
+	first, err := request(ctx, c.URL, pageURL(path, offset, limit), token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var page Page
+	err = json.Unmarshal(first, &page)
+	if err != nil {
+		return nil, err
+	}
+
+	// There is nothing else to fetch when Spotify doesn't link a next page
+	if page.Next == "" {
+		return [][]byte{first}, nil
+	}
+	if page.Limit > 0 {
+		limit = page.Limit
+	}
+
+	end := page.Total
+	if bound := offset + upTo; bound < end {
+		if bounded {
+			return nil, &transport.TooLargeError{Listing: path, Total: page.Total, Limit: upTo}
+		}
+		end = bound
+	}
+
+	// The last page is shortened so no more than the upper bound is retrieved
+	offsets := []int{}
+	limits := []int{}
+	for next := offset + limit; next < end; next += limit {
+		offsets = append(offsets, next)
+		limits = append(limits, minimum(limit, end-next))
+	}
+
+	pages := make([][]byte, len(offsets)+1)
+	pages[0] = first
+	errs := make([]error, len(offsets))
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, pageWorkers)
+	for index, next := range offsets {
+		wg.Add(1)
+		go func(index, next int) {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			pages[index+1], errs[index] = request(ctx, c.URL, pageURL(path, next, limits[index]), token, nil)
+		}(index, next)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return pages, nil
+}
+
+// pageURL adds the paging parameters to the path of a listing
+func pageURL(path string, offset, limit int) string {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%soffset=%d&limit=%d", path, separator, offset, limit)
+}
+
+// maxItems is the upper bound of items retrieved from a listing
+func (c Client) maxItems() int {
+	if c.MaxItems > 0 {
+		return c.MaxItems
+	}
+	return defaultMaxItems
+}
+
+// maxLibraryItems is the upper bound of the saved tracks of the user retrieved
+func (c Client) maxLibraryItems() int {
+	if c.MaxLibraryItems > 0 {
+		return c.MaxLibraryItems
+	}
+	return defaultMaxLibraryItems
+}
@@ -0,0 +1,90 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+// pagedTracks serves a playlist of total tracks paginated like Spotify does
+func pagedTracks(t *testing.T, total int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		items := []Track{}
+		for i := offset; i < offset+limit && i < total; i++ {
+			items = append(items, Track{Track: Playlist{ID: strconv.Itoa(i)}})
+		}
+
+		next := ""
+		if offset+limit < total {
+			next = fmt.Sprintf("%s?offset=%d&limit=%d", r.URL.Path, offset+limit, limit)
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"items":  items,
+			"next":   next,
+			"offset": offset,
+			"limit":  limit,
+			"total":  total,
+		})
+	}))
+}
+
+func TestGetTracksPagination(t *testing.T) {
+	ts := pagedTracks(t, 250)
+	defer ts.Close()
+
+	tracks, err := getTracks(context.Background(), "token", "playlist", Client{URL: ts.URL})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(tracks.Items) != 250 {
+		t.Fatalf("Expected %d, Got %d", 250, len(tracks.Items))
+	}
+	for index, item := range tracks.Items {
+		if item.Track.ID != strconv.Itoa(index) {
+			t.Fatalf("Expected track %d at position %d, Got %s", index, index, item.Track.ID)
+		}
+	}
+
+	// A listing bigger than the upper bound is reported instead of being cut off
+	_, err = getTracks(context.Background(), "token", "playlist", Client{URL: ts.URL, MaxItems: 150})
+	var tooLarge *transport.TooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("Expected a too large error, Got %v", err)
+	}
+	if tooLarge.Listing != "v1/playlists/playlist/tracks" || tooLarge.Total != 250 || tooLarge.Limit != 150 {
+		t.Errorf("Expected the listing, its total and the limit, Got %+v", tooLarge)
+	}
+}
+
+func TestSavedTracksBound(t *testing.T) {
+	ts := pagedTracks(t, 250)
+	defer ts.Close()
+
+	// The library has its own bound, so it isn't limited by the one of the other listings
+	tracks, err := Source{Kind: SourceSaved}.tracks(context.Background(), "token", Client{URL: ts.URL, MaxItems: 150})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(tracks.Items) != 250 {
+		t.Errorf("Expected %d, Got %d", 250, len(tracks.Items))
+	}
+
+	_, err = Source{Kind: SourceSaved}.tracks(context.Background(), "token", Client{URL: ts.URL, MaxItems: 150, MaxLibraryItems: 200})
+	var tooLarge *transport.TooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Listing != "v1/me/tracks" || tooLarge.Limit != 200 {
+		t.Errorf("Expected the library to be too large, Got %v", err)
+	}
+}
@@ -1,10 +1,12 @@
 package spotify
 
+import "context"
+
 // Profile gets the user information
-func (c Client) Profile(token string) (*User, error) {
+func (c Client) Profile(ctx context.Context, token string) (*User, error) {
 	// Next, we need the user.id of the current session.
 	// This is a requirement to create the new playlist.
-	user, err := userRequest(c.URL, "v1/me", token, nil)
+	user, err := userRequest(ctx, c.URL, "v1/me", token, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,44 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+// quorum creates a method keeping the tracks that appear in at least k of the playlists.
+// Every resulting track reports in Sources how many playlists contained it.
+func quorum(k int) method {
+	return func(playlists []PlaylistResponse, opts Options) ([]Playlist, error) {
+		key, err := opts.identity()
+		if err != nil {
+			return nil, err
+		}
+
+		occurrences, _ := occurrencesOf(playlists, key)
+
+		// Keep the tracks voted by enough playlists, in the order they were found
+		result := []Playlist{}
+		for _, id := range occurrences.keys {
+			if occurrences.counts[id] < k {
+				continue
+			}
+			track := occurrences.tracks[id]
+			track.Sources = occurrences.counts[id]
+			result = append(result, track)
+		}
+
+		return result, nil
+	}
+}
+
+// Quorum creates a playlist containing the tracks that appear in at least k of the playlists
+func (c Client) Quorum(ctx context.Context, token string, playlists []string, k int, name string, opts Options) (*NewPlaylistResponse, error) {
+	if k < 1 || k > len(playlists) {
+		return nil, &transport.ValidationError{Field: "k", Message: fmt.Sprintf("must be between 1 and the number of playlists (%d)", len(playlists))}
+	}
+
+	opts.report = true
+	return operation(ctx, token, playlists, name, opts, c, quorum(k))
+}
@@ -0,0 +1,137 @@
+package spotify
+
+import (
+	"expvar"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// retries counts the retried requests to Spotify by reason, and the requests that ran out of retries
+var retries = expvar.NewMap("spotify_retries")
+
+// Retries are the counts of the retried requests to Spotify, written as JSON by their String method
+func Retries() expvar.Var {
+	return retries
+}
+
+// retryTransport retries the requests to Spotify that are rate limited or fail temporarily.
+// Rate limited requests are always retried honouring the Retry-After header, since Spotify didn't process them.
+// Server errors and network failures are only retried for idempotent methods, using jittered exponential backoff.
+type retryTransport struct {
+	next http.RoundTripper
+	// maxRetries is the maximum number of retries of a single request
+	maxRetries int
+	// baseDelay is the backoff of the first retry, it doubles on every attempt
+	baseDelay time.Duration
+	// maxDelay caps the backoff of a single retry
+	maxDelay time.Duration
+	// maxElapsed caps the total time spent retrying a request
+	maxElapsed time.Duration
+}
+
+func newRetryTransport(next http.RoundTripper) *retryTransport {
+	return &retryTransport{
+		next:       next,
+		maxRetries: 5,
+		baseDelay:  250 * time.Millisecond,
+		maxDelay:   10 * time.Second,
+		maxElapsed: 30 * time.Second,
+	}
+}
+
+// RoundTrip makes the request, retrying it when possible
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	current := req
+	for attempt := 0; ; attempt++ {
+		res, err := t.next.RoundTrip(current)
+
+		reason := retryReason(req, res, err)
+		if reason == "" {
+			return res, err
+		}
+
+		wait := t.backoff(attempt, res)
+		replayable := req.Body == nil || req.GetBody != nil
+		if attempt >= t.maxRetries || time.Since(start)+wait > t.maxElapsed || !replayable {
+			retries.Add("exhausted", 1)
+			glog.Warningf("Giving up on %s %s after %d retries: %s", req.Method, req.URL.Path, attempt, reason)
+			return res, err
+		}
+
+		retries.Add(reason, 1)
+		glog.Warningf("Retrying %s %s in %s (retry %d): %s", req.Method, req.URL.Path, wait, attempt+1, reason)
+		if res != nil {
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+
+		// The body was consumed by the previous attempt, so a copy of the request with a new body is sent
+		current = req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			current.Body = body
+		}
+	}
+}
+
+// retryReason tells why a request should be retried, or returns an empty string when it shouldn't
+func retryReason(req *http.Request, res *http.Response, err error) string {
+	if req.Context().Err() != nil {
+		return ""
+	}
+
+	switch {
+	case err != nil && idempotent(req.Method):
+		return "network"
+	case err != nil:
+		return ""
+	case res.StatusCode == http.StatusTooManyRequests:
+		return "rate_limit"
+	case res.StatusCode >= 500 && idempotent(req.Method):
+		return "server_error"
+	}
+
+	return ""
+}
+
+// idempotent reports if a request can be made again without changing its effect
+func idempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// backoff computes how long to wait before retrying, the Retry-After header takes precedence when present
+func (t *retryTransport) backoff(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	ceiling := t.baseDelay << uint(attempt)
+	if ceiling <= 0 || ceiling > t.maxDelay {
+		ceiling = t.maxDelay
+	}
+
+	// Full jitter spreads the retries of concurrent requests
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
@@ -0,0 +1,76 @@
+package spotify
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// failingServer responds with the status codes in order asking to retry after the given seconds, and 200 once they run out
+func failingServer(t *testing.T, statuses []int, retryAfter string, calls *int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		if *calls <= len(statuses) {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(statuses[*calls-1])
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func testClient(maxElapsed time.Duration) *http.Client {
+	transport := newRetryTransport(http.DefaultTransport)
+	transport.baseDelay = time.Millisecond
+	transport.maxDelay = 5 * time.Millisecond
+	transport.maxElapsed = maxElapsed
+	return &http.Client{Transport: transport}
+}
+
+func TestRetryTransport(t *testing.T) {
+	cases := []struct {
+		method     string
+		statuses   []int
+		retryAfter string
+		maxElapsed time.Duration
+		calls      int
+		status     int
+		// waited is the minimum time spent retrying
+		waited time.Duration
+	}{
+		{"GET", []int{429, 503}, "0", time.Second, 3, 200, 0},
+		{"POST", []int{429}, "0", time.Second, 2, 200, 0},
+		{"POST", []int{503}, "0", time.Second, 1, 503, 0},
+		{"GET", []int{500, 500, 500, 500, 500, 500, 500}, "0", time.Second, 6, 500, 0},
+		// Retry-After is honoured instead of the backoff
+		{"GET", []int{429}, "1", 5 * time.Second, 2, 200, time.Second},
+		// A retry that would go past the maximum elapsed time is not attempted
+		{"GET", []int{429}, "1", 500 * time.Millisecond, 1, 429, 0},
+	}
+
+	for _, c := range cases {
+		calls := 0
+		ts := failingServer(t, c.statuses, c.retryAfter, &calls)
+
+		req, _ := http.NewRequest(c.method, ts.URL, bytes.NewBufferString("{}"))
+		start := time.Now()
+		res, err := testClient(c.maxElapsed).Do(req)
+		elapsed := time.Since(start)
+		ts.Close()
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err)
+			continue
+		}
+		res.Body.Close()
+
+		if calls != c.calls || res.StatusCode != c.status {
+			t.Errorf("%s %v: Expected %d calls and status %d, Got %d and %d", c.method, c.statuses, c.calls, c.status, calls, res.StatusCode)
+		}
+		if elapsed < c.waited {
+			t.Errorf("%s %v: Expected to wait at least %s, Got %s", c.method, c.statuses, c.waited, elapsed)
+		}
+	}
+}
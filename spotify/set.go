@@ -0,0 +1,91 @@
+package spotify
+
+// trackSet is an ordered multiset of tracks keyed by their identity.
+// Tracks keep the order in which they were first added, so every operation built on it is deterministic.
+type trackSet struct {
+	key    identity
+	keys   []string
+	tracks map[string]Playlist
+	counts map[string]int
+}
+
+func newTrackSet(key identity) *trackSet {
+	return &trackSet{
+		key:    key,
+		keys:   []string{},
+		tracks: map[string]Playlist{},
+		counts: map[string]int{},
+	}
+}
+
+// setOf creates the set of tracks of a playlist, counting how many times every track appears
+func setOf(playlist PlaylistResponse, key identity) *trackSet {
+	set := newTrackSet(key)
+	for _, item := range playlist.Items {
+		set.add(item.Track, 1)
+	}
+	return set
+}
+
+// add inserts count occurrences of the track, the first occurrence of a track is the one kept
+func (s *trackSet) add(track Playlist, count int) {
+	if count <= 0 {
+		return
+	}
+	key := s.key(track)
+	if _, ok := s.tracks[key]; !ok {
+		s.keys = append(s.keys, key)
+		s.tracks[key] = track
+	}
+	s.counts[key] += count
+}
+
+// count returns how many times a track with the same identity is in the set
+func (s *trackSet) count(track Playlist) int {
+	return s.counts[s.key(track)]
+}
+
+// contains reports if a track with the same identity is in the set
+func (s *trackSet) contains(track Playlist) bool {
+	return s.count(track) > 0
+}
+
+// items returns the tracks of the set in insertion order, repeating every track as many times as it was counted
+func (s *trackSet) items() []Playlist {
+	items := make([]Playlist, 0, len(s.keys))
+	for _, key := range s.keys {
+		for i := 0; i < s.counts[key]; i++ {
+			items = append(items, s.tracks[key])
+		}
+	}
+	return items
+}
+
+// occurrencesOf counts in how many playlists every track appears, a track repeated inside the same playlist only counts once.
+// The tracks keep the order in which they were found, and the set of every playlist is returned along with them.
+func occurrencesOf(playlists []PlaylistResponse, key identity) (*trackSet, []*trackSet) {
+	occurrences := newTrackSet(key)
+	sets := []*trackSet{}
+	for _, playlist := range playlists {
+		set := setOf(playlist, key)
+		for _, id := range set.keys {
+			occurrences.add(set.tracks[id], 1)
+		}
+		sets = append(sets, set)
+	}
+	return occurrences, sets
+}
+
+func minimum(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maximum(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
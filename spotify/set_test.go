@@ -0,0 +1,208 @@
+package spotify
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// playlistOf creates a playlist response with tracks named after the given ids
+func playlistOf(ids ...string) PlaylistResponse {
+	tracks := []Playlist{}
+	for _, id := range ids {
+		tracks = append(tracks, Playlist{ID: id, Name: "Track " + id, URI: "spotify:track:" + id})
+	}
+	return toPlaylistResponse(tracks)
+}
+
+// largePlaylist creates a playlist of size tracks starting at the given track number
+func largePlaylist(start, size int) PlaylistResponse {
+	ids := []string{}
+	for i := start; i < start+size; i++ {
+		ids = append(ids, fmt.Sprintf("%022d", i))
+	}
+	return playlistOf(ids...)
+}
+
+func idsOf(tracks []Playlist) []string {
+	ids := []string{}
+	for _, track := range tracks {
+		ids = append(ids, track.ID)
+	}
+	return ids
+}
+
+func TestSetOperations(t *testing.T) {
+	a := playlistOf("1", "2", "3", "2", "5")
+	b := playlistOf("5", "4", "3", "2")
+	c := playlistOf("2", "6", "5")
+
+	cases := []struct {
+		name     string
+		fn       method
+		expected []string
+	}{
+		{"intersection", fold(intersect), []string{"2", "5"}},
+		{"complement", complementAll, []string{"4", "6"}},
+		{"difference", symmetricDifference, []string{"1", "4", "6"}},
+	}
+
+	for _, tc := range cases {
+		result, err := tc.fn([]PlaylistResponse{a, b, c}, Options{})
+		if err != nil {
+			t.Errorf("Unexpected error in %s: %s", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(idsOf(result), tc.expected) {
+			t.Errorf("%s: Expected %v, Got %v", tc.name, tc.expected, idsOf(result))
+		}
+	}
+
+	// The operands must not be modified
+	if !reflect.DeepEqual(c, playlistOf("2", "6", "5")) {
+		t.Errorf("Operands were modified by the set operations")
+	}
+}
+
+func TestBagSemantics(t *testing.T) {
+	a := playlistOf("1", "1", "1", "2", "3")
+	b := playlistOf("1", "1", "2", "2", "4")
+
+	cases := []struct {
+		name      string
+		fn        method
+		semantics string
+		expected  []string
+	}{
+		{"distinct union", fold(unify), SemanticsDistinct, []string{"1", "2", "3", "4"}},
+		{"bag union", fold(unify), SemanticsBag, []string{"1", "1", "1", "1", "1", "2", "2", "2", "3", "4"}},
+		{"bag-max union", fold(unify), SemanticsBagMax, []string{"1", "1", "1", "2", "2", "3", "4"}},
+		{"bag intersection", fold(intersect), SemanticsBag, []string{"1", "1", "2"}},
+		{"bag complement", complementAll, SemanticsBag, []string{"2", "4"}},
+		{"distinct complement", complementAll, SemanticsDistinct, []string{"4"}},
+	}
+
+	for _, c := range cases {
+		result, err := c.fn([]PlaylistResponse{a, b}, Options{Semantics: c.semantics})
+		if err != nil {
+			t.Errorf("Unexpected error in %s: %s", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(idsOf(result), c.expected) {
+			t.Errorf("%s: Expected %v, Got %v", c.name, c.expected, idsOf(result))
+		}
+	}
+}
+
+func TestQuorum(t *testing.T) {
+	playlists := []PlaylistResponse{
+		playlistOf("1", "2", "3", "3"),
+		playlistOf("3", "4", "2"),
+		playlistOf("4", "3", "5"),
+	}
+
+	result, err := quorum(2)(playlists, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := []string{"2", "3", "4"}
+	if !reflect.DeepEqual(idsOf(result), expected) {
+		t.Errorf("Expected %v, Got %v", expected, idsOf(result))
+	}
+
+	sources := []int{result[0].Sources, result[1].Sources, result[2].Sources}
+	if !reflect.DeepEqual(sources, []int{2, 3, 2}) {
+		t.Errorf("Expected %v, Got %v", []int{2, 3, 2}, sources)
+	}
+}
+
+func TestIdentities(t *testing.T) {
+	original := Playlist{
+		ID:          "1",
+		Name:        "Heroes",
+		Artists:     []Artist{{Name: "David Bowie"}},
+		ExternalIDs: &ExternalIDs{ISRC: "gbaye7700018"},
+	}
+	remaster := Playlist{
+		ID:          "2",
+		Name:        "\"Heroes\" - 2017 Remaster",
+		Artists:     []Artist{{Name: "David Bowie"}, {Name: "Brian Eno"}},
+		ExternalIDs: &ExternalIDs{ISRC: "GBAYE7700018"},
+	}
+	featuring := Playlist{ID: "3", Name: "Heroes (feat. Someone)", Artists: []Artist{{Name: "david bowie"}}}
+	cover := Playlist{ID: "4", Name: "Heroes", Artists: []Artist{{Name: "Peter Gabriel"}}}
+
+	cases := []struct {
+		match    string
+		a, b     Playlist
+		expected bool
+	}{
+		{"id", original, remaster, false},
+		{"isrc", original, remaster, true},
+		{"isrc", original, featuring, false},
+		{"title", original, remaster, true},
+		{"title", original, featuring, true},
+		{"title", original, cover, false},
+	}
+
+	for _, c := range cases {
+		key := identities[c.match]
+		if (key(c.a) == key(c.b)) != c.expected {
+			t.Errorf("%s: Expected %q and %q to match: %t", c.match, c.a.Name, c.b.Name, c.expected)
+		}
+	}
+
+	if _, err := (Options{Match: "unknown"}).identity(); err == nil {
+		t.Errorf("Expected an error with an unknown match strategy")
+	}
+}
+
+func TestSetOperationsConcurrent(t *testing.T) {
+	a := largePlaylist(0, 1000)
+	b := largePlaylist(500, 1000)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := fold(intersect)([]PlaylistResponse{a, b}, Options{})
+			if err != nil {
+				t.Errorf("Unexpected error: %s", err)
+				return
+			}
+			if len(result) != 500 || result[0].ID != a.Items[500].Track.ID {
+				t.Errorf("Expected 500 tracks starting at %s, Got %d", a.Items[500].Track.ID, len(result))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func benchmarkMethod(b *testing.B, fn method) {
+	playlists := []PlaylistResponse{largePlaylist(0, 10000), largePlaylist(5000, 10000)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := fn(playlists, Options{}); err != nil {
+			b.Fatalf("Unexpected error: %s", err)
+		}
+	}
+}
+
+func BenchmarkIntersect(b *testing.B) {
+	benchmarkMethod(b, fold(intersect))
+}
+
+func BenchmarkUnion(b *testing.B) {
+	benchmarkMethod(b, fold(unify))
+}
+
+func BenchmarkComplement(b *testing.B) {
+	benchmarkMethod(b, complementAll)
+}
+
+func BenchmarkSymmetricDifference(b *testing.B) {
+	benchmarkMethod(b, symmetricDifference)
+}
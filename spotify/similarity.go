@@ -0,0 +1,211 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+// Metrics used to measure the similarity between two playlists
+const (
+	// MetricJaccard is the size of the intersection divided by the size of the union
+	MetricJaccard = "jaccard"
+	// MetricOverlap is the size of the intersection divided by the size of the smallest playlist,
+	// a playlist contained in another one has an overlap of 1
+	MetricOverlap = "overlap"
+)
+
+// defaultTopPairs is the number of pairs listed when the request doesn't specify one
+const defaultTopPairs = 10
+
+// Similarity is the pairwise similarity between the playlists of a user
+type Similarity struct {
+	// Playlists are the rows and columns of the matrix
+	Playlists []Playlist  `json:"playlists"`
+	Metric    string      `json:"metric"`
+	Matrix    [][]float64 `json:"matrix"`
+	// Pairs are the most similar pairs of playlists, from the most similar one
+	Pairs []Pair `json:"pairs"`
+	// Skipped are the playlists whose tracks couldn't be retrieved, they are not part of the matrix
+	Skipped []SkippedPlaylist `json:"skipped"`
+}
+
+// SkippedPlaylist is a playlist left out of the similarity, along with the reason
+type SkippedPlaylist struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Pair is the similarity between two playlists
+type Pair struct {
+	First   string  `json:"first"`
+	Second  string  `json:"second"`
+	Shared  int     `json:"shared"`
+	Jaccard float64 `json:"jaccard"`
+	Overlap float64 `json:"overlap"`
+}
+
+// Similarity compares every playlist the user owns or follows with each other,
+// returning the matrix of the metric and the top most similar pairs
+func (c Client) Similarity(ctx context.Context, token string, top int, metric string, opts Options) (*Similarity, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	key, err := opts.identity()
+	if err != nil {
+		return nil, err
+	}
+
+	if metric == "" {
+		metric = MetricJaccard
+	}
+	if metric != MetricJaccard && metric != MetricOverlap {
+		return nil, &transport.ValidationError{Field: "metric", Message: fmt.Sprintf("unknown metric %q", metric)}
+	}
+	if top <= 0 {
+		top = defaultTopPairs
+	}
+
+	// Page through every playlist of the user
+	playlists, err := getPlaylists(ctx, token, 0, 0, "me", c)
+	if err != nil {
+		return nil, err
+	}
+
+	readable, sets, skipped, err := playlistSets(ctx, token, playlists.Items, key, opts.Match, c)
+	if err != nil {
+		return nil, err
+	}
+
+	similarity := Similarity{
+		Playlists: summaryOfPlaylists(readable),
+		Metric:    metric,
+		Matrix:    make([][]float64, len(sets)),
+		Pairs:     []Pair{},
+		Skipped:   skipped,
+	}
+	for i := range sets {
+		similarity.Matrix[i] = make([]float64, len(sets))
+		if len(sets[i]) > 0 {
+			similarity.Matrix[i][i] = 1
+		}
+	}
+
+	for i := range sets {
+		for j := i + 1; j < len(sets); j++ {
+			pair := pairOf(readable[i].ID, readable[j].ID, sets[i], sets[j])
+			value := pair.Jaccard
+			if metric == MetricOverlap {
+				value = pair.Overlap
+			}
+			similarity.Matrix[i][j] = value
+			similarity.Matrix[j][i] = value
+
+			if pair.Shared > 0 {
+				similarity.Pairs = append(similarity.Pairs, pair)
+			}
+		}
+	}
+
+	// The most similar pairs go first, the number of shared tracks breaks the ties
+	sort.SliceStable(similarity.Pairs, func(i, j int) bool {
+		a, b := similarity.Pairs[i], similarity.Pairs[j]
+		if metric == MetricOverlap && a.Overlap != b.Overlap {
+			return a.Overlap > b.Overlap
+		}
+		if a.Jaccard != b.Jaccard {
+			return a.Jaccard > b.Jaccard
+		}
+		return a.Shared > b.Shared
+	})
+	if len(similarity.Pairs) > top {
+		similarity.Pairs = similarity.Pairs[:top]
+	}
+
+	return &similarity, nil
+}
+
+// playlistSets fetches the tracks of every playlist at the same time, using the cache of the client,
+// and keeps the distinct keys of the tracks of each one.
+// The playlists whose tracks can't be retrieved, like followed playlists the token can't read, are skipped;
+// only a rejected token or a cancelled request fail the whole comparison.
+func playlistSets(ctx context.Context, token string, playlists []Playlist, key identity, match string, c Client) ([]Playlist, []map[string]bool, []SkippedPlaylist, error) {
+	sets := make([]map[string]bool, len(playlists))
+	errs := make([]error, len(playlists))
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, pageWorkers)
+	for index, playlist := range playlists {
+		wg.Add(1)
+		go func(index int, playlist Playlist) {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+
+			sets[index], errs[index] = cachedPlaylistKeys(ctx, token, playlist, key, match, c)
+		}(index, playlist)
+	}
+	wg.Wait()
+
+	readable := []Playlist{}
+	readableSets := []map[string]bool{}
+	skipped := []SkippedPlaylist{}
+	for index, err := range errs {
+		var auth *transport.AuthError
+		switch {
+		case err == nil:
+			readable = append(readable, playlists[index])
+			readableSets = append(readableSets, sets[index])
+		case errors.As(err, &auth), ctx.Err() != nil:
+			return nil, nil, nil, err
+		default:
+			skipped = append(skipped, SkippedPlaylist{ID: playlists[index].ID, Name: playlists[index].Name, Reason: err.Error()})
+		}
+	}
+
+	return readable, readableSets, skipped, nil
+}
+
+// pairOf measures the similarity between the sets of tracks of two playlists
+func pairOf(first, second string, a, b map[string]bool) Pair {
+	// Look up the tracks of the smallest set in the biggest one
+	small, big := a, b
+	if len(small) > len(big) {
+		small, big = big, small
+	}
+
+	pair := Pair{First: first, Second: second}
+	for track := range small {
+		if big[track] {
+			pair.Shared++
+		}
+	}
+
+	if union := len(a) + len(b) - pair.Shared; union > 0 {
+		pair.Jaccard = float64(pair.Shared) / float64(union)
+	}
+	if len(small) > 0 {
+		pair.Overlap = float64(pair.Shared) / float64(len(small))
+	}
+
+	return pair
+}
+
+// summaryOfPlaylists keeps the fields that identify each playlist
+func summaryOfPlaylists(playlists []Playlist) []Playlist {
+	summary := make([]Playlist, 0, len(playlists))
+	for _, playlist := range playlists {
+		summary = append(summary, Playlist{
+			ID:     playlist.ID,
+			Name:   playlist.Name,
+			Owner:  playlist.Owner,
+			Tracks: playlist.Tracks,
+		})
+	}
+	return summary
+}
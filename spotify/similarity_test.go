@@ -0,0 +1,185 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+// fakeLibrary serves the playlists of a user sorted by ID and their tracks, counting how many times the tracks of each playlist were fetched.
+// Playlists without tracks can't be read and respond with a 403.
+func fakeLibrary(t *testing.T, playlists map[string][]string, fetched map[string]int, mutex *sync.Mutex) *httptest.Server {
+	t.Helper()
+	ids := []string{}
+	for id := range playlists {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/me/playlists":
+			items := []PlaylistDecoder{}
+			for _, id := range ids {
+				items = append(items, PlaylistDecoder{ID: id, Name: "Playlist " + id, Snapshot: "snapshot-" + id})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"items": items, "total": len(items)})
+		case strings.HasPrefix(r.URL.Path, "/v1/playlists/"):
+			id := strings.Split(r.URL.Path, "/")[3]
+			mutex.Lock()
+			fetched[id]++
+			mutex.Unlock()
+			if playlists[id] == nil {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			json.NewEncoder(w).Encode(playlistOf(playlists[id]...))
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	}))
+}
+
+func TestSimilarity(t *testing.T) {
+	playlists := map[string][]string{
+		"a": {"1", "2", "3", "4"},
+		"b": {"3", "4"},
+		"c": {"4", "5", "6", "7"},
+	}
+	fetched := map[string]int{}
+	var mutex sync.Mutex
+	ts := fakeLibrary(t, playlists, fetched, &mutex)
+	defer ts.Close()
+
+	c := Client{URL: ts.URL, cache: newTrackCache()}
+	similarity, err := c.Similarity(context.Background(), "token", 2, MetricOverlap, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// b is contained in a, which makes it the most overlapping pair
+	if len(similarity.Pairs) != 2 {
+		t.Fatalf("Expected %d pairs, Got %d", 2, len(similarity.Pairs))
+	}
+	if first := similarity.Pairs[0]; first.First != "a" || first.Second != "b" || first.Overlap != 1 || first.Shared != 2 {
+		t.Errorf("Expected a and b to overlap completely, Got %+v", first)
+	}
+	if math.Abs(similarity.Matrix[0][2]-0.25) > 1e-9 || similarity.Matrix[0][2] != similarity.Matrix[2][0] {
+		t.Errorf("Expected a symmetric overlap of %v between a and c, Got %v", 0.25, similarity.Matrix)
+	}
+	if similarity.Matrix[1][1] != 1 {
+		t.Errorf("Expected %v, Got %v", 1, similarity.Matrix[1][1])
+	}
+
+	// The tracks are cached by snapshot, so they are not fetched again
+	if _, err = c.Similarity(context.Background(), "token", 0, "", Options{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for id, count := range fetched {
+		if count != 1 {
+			t.Errorf("Expected the tracks of %s to be fetched once, Got %d", id, count)
+		}
+	}
+}
+
+func TestSimilaritySkipsUnreadablePlaylists(t *testing.T) {
+	playlists := map[string][]string{
+		"a": {"1", "2"},
+		"b": nil,
+		"c": {"2", "3"},
+	}
+	fetched := map[string]int{}
+	var mutex sync.Mutex
+	ts := fakeLibrary(t, playlists, fetched, &mutex)
+	defer ts.Close()
+
+	similarity, err := Client{URL: ts.URL}.Similarity(context.Background(), "token", 0, "", Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// The playlist that can't be read is left out of the matrix
+	if len(similarity.Playlists) != 2 || similarity.Playlists[0].ID != "a" || similarity.Playlists[1].ID != "c" {
+		t.Errorf("Expected %v, Got %v", []string{"a", "c"}, similarity.Playlists)
+	}
+	if len(similarity.Matrix) != 2 || math.Abs(similarity.Matrix[0][1]-1.0/3) > 1e-9 {
+		t.Errorf("Expected a jaccard of %v between a and c, Got %v", 1.0/3, similarity.Matrix)
+	}
+	if len(similarity.Skipped) != 1 || similarity.Skipped[0].ID != "b" || similarity.Skipped[0].Reason == "" {
+		t.Errorf("Expected b to be skipped, Got %+v", similarity.Skipped)
+	}
+}
+
+func TestSimilarityUnauthorized(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/me/playlists" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"items": []PlaylistDecoder{{ID: "a"}, {ID: "b"}}, "total": 2})
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	// A rejected token fails the whole comparison instead of skipping every playlist
+	_, err := Client{URL: ts.URL}.Similarity(context.Background(), "token", 0, "", Options{})
+	if _, ok := err.(*transport.AuthError); !ok {
+		t.Errorf("Expected %T, Got %v", &transport.AuthError{}, err)
+	}
+}
+
+func TestSimilarityUnknownMetric(t *testing.T) {
+	if _, err := (Client{}).Similarity(context.Background(), "token", 0, "cosine", Options{}); err == nil {
+		t.Errorf("Expected an error with an unknown metric")
+	}
+}
+
+func TestTrackCache(t *testing.T) {
+	cache := newTrackCache()
+	keys := map[string]bool{"1": true}
+
+	cache.put("a", "1", "", keys, 2)
+	if _, ok := cache.get("a", "2", ""); ok {
+		t.Errorf("Expected a different snapshot to miss the cache")
+	}
+	if _, ok := cache.get("a", "1", "isrc"); ok {
+		t.Errorf("Expected a different match to miss the cache")
+	}
+	if cached, ok := cache.get("a", "1", ""); !ok || !reflect.DeepEqual(cached, keys) {
+		t.Errorf("Expected %v, Got %v", keys, cached)
+	}
+
+	// The oldest playlist is evicted once the cache holds more keys than its size
+	cache.put("b", "1", "", keys, 2)
+	cache.put("c", "1", "", keys, 2)
+	if _, ok := cache.get("a", "1", ""); ok {
+		t.Errorf("Expected a to be evicted")
+	}
+	if _, ok := cache.get("c", "1", ""); !ok {
+		t.Errorf("Expected c to be cached")
+	}
+
+	// A playlist bigger than the whole cache is not stored
+	cache.put("d", "1", "", map[string]bool{"1": true, "2": true, "3": true}, 2)
+	if _, ok := cache.get("d", "1", ""); ok {
+		t.Errorf("Expected d not to be cached")
+	}
+	if cache.keys != 2 {
+		t.Errorf("Expected %d, Got %d", 2, cache.keys)
+	}
+
+	// A nil cache never has anything
+	var empty *trackCache
+	empty.put("a", "1", "", keys, 2)
+	if _, ok := empty.get("a", "1", ""); ok {
+		t.Errorf("Expected a nil cache to miss")
+	}
+}
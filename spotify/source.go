@@ -0,0 +1,263 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+// Kinds of the sources whose tracks can be used as operands of the set operations
+const (
+	// SourcePlaylist are the tracks of a playlist
+	SourcePlaylist = KindPlaylist
+	// SourceSaved are the saved tracks of the current user, known as Liked Songs
+	SourceSaved = "saved"
+	// SourceAlbum are the tracks of an album
+	SourceAlbum = KindAlbum
+	// SourceTopTracks are the top tracks of an artist
+	SourceTopTracks = "top"
+	// SourceDiscography are the tracks of every album and single of an artist
+	SourceDiscography = "discography"
+	// SourceRecent are the tracks recently played by the current user
+	SourceRecent = "recent"
+)
+
+// sourceKinds are the kinds of object each source expects after its prefix
+var sourceKinds = map[string]string{
+	SourcePlaylist:    KindPlaylist,
+	SourceAlbum:       KindAlbum,
+	SourceTopTracks:   KindArtist,
+	SourceDiscography: KindArtist,
+}
+
+// Source references a list of tracks used as an operand.
+// It is written as a playlist ID, URI or URL, as "saved" or "recent" for the libraries of the current user,
+// or as a prefixed reference such as "album:ID", "top:ID" or "discography:ID" where the ID can also be a URI or URL.
+// Album URIs are read as albums and artist URIs as the top tracks of the artist.
+type Source struct {
+	Kind string
+	ID   string
+}
+
+// String writes the source as a reference ParseSource understands
+func (s Source) String() string {
+	if s.ID == "" {
+		return s.Kind
+	}
+	return fmt.Sprintf("%s:%s", s.Kind, s.ID)
+}
+
+// ParseSource reads the reference of a source
+func ParseSource(value string) (Source, error) {
+	value = strings.TrimSpace(value)
+	switch value {
+	case SourceSaved, "liked":
+		return Source{Kind: SourceSaved}, nil
+	case SourceRecent:
+		return Source{Kind: SourceRecent}, nil
+	}
+
+	// Prefixed references say which source they are, the rest is the ID of the object they expect
+	if index := strings.Index(value, ":"); index >= 0 {
+		if kind, ok := sourceKinds[value[:index]]; ok {
+			identifier, err := ParseIdentifier(value[index+1:])
+			if err != nil {
+				return Source{}, err
+			}
+			if identifier.Kind != "" && identifier.Kind != kind {
+				return Source{}, fmt.Errorf("expected a %s but %q references a %s", kind, value, identifier.Kind)
+			}
+			return Source{Kind: value[:index], ID: identifier.ID}, nil
+		}
+	}
+
+	identifier, err := ParseIdentifier(value)
+	if err != nil {
+		return Source{}, err
+	}
+
+	switch identifier.Kind {
+	case "", KindPlaylist:
+		return Source{Kind: SourcePlaylist, ID: identifier.ID}, nil
+	case KindAlbum:
+		return Source{Kind: SourceAlbum, ID: identifier.ID}, nil
+	case KindArtist:
+		return Source{Kind: SourceTopTracks, ID: identifier.ID}, nil
+	}
+
+	return Source{}, fmt.Errorf("%q references a %s, which has no tracks to operate with", value, identifier.Kind)
+}
+
+// sourcesOf parses every source of an operation, reporting the field of the request with all the invalid ones
+func sourcesOf(field string, values []string) ([]Source, error) {
+	sources := []Source{}
+	invalid := transport.ValidationErrors{}
+	for _, value := range values {
+		source, err := ParseSource(value)
+		if err != nil {
+			invalid = append(invalid, &transport.ValidationError{Field: field, Message: err.Error()})
+			continue
+		}
+		sources = append(sources, source)
+	}
+	if len(invalid) > 0 {
+		return nil, invalid
+	}
+
+	return sources, nil
+}
+
+// tracks retrieves the tracks of the source
+func (s Source) tracks(ctx context.Context, token string, c Client) (*PlaylistResponse, error) {
+	switch s.Kind {
+	case SourcePlaylist:
+		return getTracks(ctx, token, s.ID, c)
+	case SourceSaved:
+		return savedTracks(ctx, token, c)
+	case SourceAlbum:
+		return albumTracks(ctx, token, s.ID, c)
+	case SourceTopTracks:
+		return topTracks(ctx, token, s.ID, c)
+	case SourceDiscography:
+		return discography(ctx, token, s.ID, c)
+	case SourceRecent:
+		return recentTracks(ctx, token, c)
+	}
+
+	return nil, &transport.ValidationError{Field: "playlists", Message: fmt.Sprintf("unknown source %q", s.Kind)}
+}
+
+// albumTracks retrieves the tracks of an album, which Spotify lists without the album they belong to
+func albumTracks(ctx context.Context, token, id string, c Client) (*PlaylistResponse, error) {
+	body, err := request(ctx, c.URL, fmt.Sprintf("v1/albums/%s", id), token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var album Album
+	err = json.Unmarshal(body, &album)
+	if err != nil {
+		return nil, err
+	}
+
+	return albumTracksOf(ctx, token, album, c)
+}
+
+// albumTracksOf retrieves the tracks of an album that was already retrieved, setting the album of every track
+func albumTracksOf(ctx context.Context, token string, album Album, c Client) (*PlaylistResponse, error) {
+	pages, err := paginate(ctx, token, fmt.Sprintf("v1/albums/%s/tracks", album.ID), 0, 50, 0, c)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := PlaylistResponse{
+		Reference: fmt.Sprintf("%s/v1/albums/%s", c.URL, album.ID),
+		Items:     []Track{},
+	}
+	for _, page := range pages {
+		var albumPage struct {
+			Items []Playlist `json:"items"`
+		}
+		err = json.Unmarshal(page, &albumPage)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, track := range albumPage.Items {
+			track.Album = &album
+			tracks.Items = append(tracks.Items, Track{Track: track})
+		}
+	}
+
+	return &tracks, nil
+}
+
+// savedTracks retrieves the saved tracks of the current user, which are bounded by MaxLibraryItems instead of MaxItems
+func savedTracks(ctx context.Context, token string, c Client) (*PlaylistResponse, error) {
+	c.MaxItems = c.maxLibraryItems()
+	return listTracks(ctx, token, "v1/me/tracks", 50, c)
+}
+
+// topTracks retrieves the top tracks of an artist in the market of the current user
+func topTracks(ctx context.Context, token, id string, c Client) (*PlaylistResponse, error) {
+	path := fmt.Sprintf("v1/artists/%s/top-tracks?market=from_token", id)
+	body, err := request(ctx, c.URL, path, token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var top struct {
+		Tracks []Playlist `json:"tracks"`
+	}
+	err = json.Unmarshal(body, &top)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := PlaylistResponse{
+		Reference: fmt.Sprintf("%s/%s", c.URL, path),
+		Items:     []Track{},
+	}
+	for _, track := range top.Tracks {
+		tracks.Items = append(tracks.Items, Track{Track: track})
+	}
+
+	return &tracks, nil
+}
+
+// discography retrieves the tracks of every album and single of an artist, from the newest release to the oldest
+func discography(ctx context.Context, token, id string, c Client) (*PlaylistResponse, error) {
+	path := fmt.Sprintf("v1/artists/%s/albums?include_groups=album,single", id)
+	pages, err := paginate(ctx, token, path, 0, 50, 0, c)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := PlaylistResponse{
+		Reference: fmt.Sprintf("%s/v1/artists/%s/albums", c.URL, id),
+		Items:     []Track{},
+	}
+	for _, page := range pages {
+		var albums struct {
+			Items []Album `json:"items"`
+		}
+		err = json.Unmarshal(page, &albums)
+		if err != nil {
+			return nil, err
+		}
+
+		// The albums of an artist already include their release date, so only their tracks are retrieved
+		for _, album := range albums.Items {
+			albumResponse, err := albumTracksOf(ctx, token, album, c)
+			if err != nil {
+				return nil, err
+			}
+			tracks.Items = append(tracks.Items, albumResponse.Items...)
+		}
+	}
+
+	return &tracks, nil
+}
+
+// recentTracks retrieves the last tracks played by the current user, a track appears once for every time it was played
+func recentTracks(ctx context.Context, token string, c Client) (*PlaylistResponse, error) {
+	// Spotify only keeps the last 50 tracks played
+	body, err := request(ctx, c.URL, "v1/me/player/recently-played?limit=50", token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var tracks PlaylistResponse
+	err = json.Unmarshal(body, &tracks)
+	if err != nil {
+		return nil, err
+	}
+	if tracks.Items == nil {
+		tracks.Items = []Track{}
+	}
+
+	return &tracks, nil
+}
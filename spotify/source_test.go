@@ -0,0 +1,120 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseSource(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected Source
+	}{
+		{"37i9dQZF1DXcBWIGoYBM5M", Source{Kind: SourcePlaylist, ID: "37i9dQZF1DXcBWIGoYBM5M"}},
+		{"https://open.spotify.com/playlist/37i9dQZF1DXcBWIGoYBM5M?si=d2f9a1", Source{Kind: SourcePlaylist, ID: "37i9dQZF1DXcBWIGoYBM5M"}},
+		{"saved", Source{Kind: SourceSaved}},
+		{"liked", Source{Kind: SourceSaved}},
+		{"recent", Source{Kind: SourceRecent}},
+		{"spotify:album:4aawyAB9vmqN3uQ7FjRGTy", Source{Kind: SourceAlbum, ID: "4aawyAB9vmqN3uQ7FjRGTy"}},
+		{"album:4aawyAB9vmqN3uQ7FjRGTy", Source{Kind: SourceAlbum, ID: "4aawyAB9vmqN3uQ7FjRGTy"}},
+		{"spotify:artist:0TnOYISbd1XYRBk9myaseg", Source{Kind: SourceTopTracks, ID: "0TnOYISbd1XYRBk9myaseg"}},
+		{"top:0TnOYISbd1XYRBk9myaseg", Source{Kind: SourceTopTracks, ID: "0TnOYISbd1XYRBk9myaseg"}},
+		{"discography:spotify:artist:0TnOYISbd1XYRBk9myaseg", Source{Kind: SourceDiscography, ID: "0TnOYISbd1XYRBk9myaseg"}},
+		{"playlist:https://open.spotify.com/playlist/37i9dQZF1DXcBWIGoYBM5M", Source{Kind: SourcePlaylist, ID: "37i9dQZF1DXcBWIGoYBM5M"}},
+	}
+
+	for _, c := range cases {
+		source, err := ParseSource(c.value)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %s", c.value, err)
+			continue
+		}
+		if source != c.expected {
+			t.Errorf("Expected %v, Got %v", c.expected, source)
+		}
+
+		// The reference of a source parses back to the same source
+		if parsed, err := ParseSource(source.String()); err != nil || parsed != source {
+			t.Errorf("Expected %v, Got %v", source, parsed)
+		}
+	}
+}
+
+func TestParseSourceErrors(t *testing.T) {
+	cases := []string{"", "saved!", "spotify:track:4uLU6hMCjMI75M1A2tKUQC", "spotify:user:jacob", "album:spotify:artist:0TnOYISbd1XYRBk9myaseg", "discography:", "album:https://notspotify.com/album/4aawyAB9vmqN3uQ7FjRGTy"}
+
+	for _, value := range cases {
+		if _, err := ParseSource(value); err == nil {
+			t.Errorf("Expected an error parsing %q", value)
+		}
+	}
+}
+
+func TestAlbumTracks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/albums/album":
+			json.NewEncoder(w).Encode(Album{ID: "album", Name: "Album", ReleaseDate: "1999"})
+		case "/v1/albums/album/tracks":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"items": []Playlist{{ID: "1"}, {ID: "2"}},
+				"total": 2,
+			})
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	tracks, err := Source{Kind: SourceAlbum, ID: "album"}.tracks(context.Background(), "token", Client{URL: ts.URL})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if len(tracks.Items) != 2 {
+		t.Fatalf("Expected %d, Got %d", 2, len(tracks.Items))
+	}
+	for _, item := range tracks.Items {
+		if item.Track.Album == nil || item.Track.Album.ReleaseDate != "1999" {
+			t.Errorf("Expected the album of track %s, Got %v", item.Track.ID, item.Track.Album)
+		}
+	}
+}
+
+func TestDiscography(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/artists/artist/albums":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"items": []Album{{ID: "new", ReleaseDate: "2020"}, {ID: "old", ReleaseDate: "1999"}},
+				"total": 2,
+			})
+		case "/v1/albums/new/tracks":
+			json.NewEncoder(w).Encode(map[string]interface{}{"items": []Playlist{{ID: "1"}}, "total": 1})
+		case "/v1/albums/old/tracks":
+			json.NewEncoder(w).Encode(map[string]interface{}{"items": []Playlist{{ID: "2"}}, "total": 1})
+		default:
+			// The albums of the artist are not retrieved again
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	tracks, err := Source{Kind: SourceDiscography, ID: "artist"}.tracks(context.Background(), "token", Client{URL: ts.URL})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := map[string]string{"1": "2020", "2": "1999"}
+	if len(tracks.Items) != len(expected) {
+		t.Fatalf("Expected %d, Got %d", len(expected), len(tracks.Items))
+	}
+	for _, item := range tracks.Items {
+		if item.Track.Album == nil || item.Track.Album.ReleaseDate != expected[item.Track.ID] {
+			t.Errorf("Expected the album of track %s, Got %v", item.Track.ID, item.Track.Album)
+		}
+	}
+}
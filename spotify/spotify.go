@@ -2,11 +2,11 @@ package spotify
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"net/url"
 
 	"github.com/jacobgarcia/settify/transport"
 )
@@ -18,11 +18,15 @@ func New(a, u, i, s string) *Client {
 		URL:     u,
 		id:      i,
 		secret:  s,
+		cache:   newTrackCache(),
 	}
 }
 
 // method is a custom type abstrction in order to pass functions as parameters
-type method func(first PlaylistResponse, second PlaylistResponse) ([]Playlist, error)
+type method func(playlists []PlaylistResponse, opts Options) ([]Playlist, error)
+
+// binary is a set operation between exactly two playlists, it can be turned into a method using fold
+type binary func(first PlaylistResponse, second PlaylistResponse, opts Options) ([]Playlist, error)
 
 // Client contains the required params to connect succesfully to Spotify API
 type Client struct {
@@ -30,18 +34,31 @@ type Client struct {
 	URL     string
 	id      string
 	secret  string
+	// MaxItems is the upper bound of items retrieved when paging through a listing
+	MaxItems int
+	// MaxLibraryItems is the upper bound of the saved tracks retrieved from the library of the user
+	MaxLibraryItems int
+	// CacheSize is the number of track keys cached, counted across every playlist
+	CacheSize int
+	// cache keeps the keys of the tracks of the playlists by snapshot, there is no cache when it is nil
+	cache *trackCache
 }
 
 // Service expose all endpoints as services
 // This is a microservices architecture pattern
 type Service interface {
-	UserPlaylists(token, offset, username string) (*Playlists, error)
-	Profile(token string) (*User, error)
-	Playlists(token string, offset string) (*Playlists, error)
-	Playlist(token, id string) (*Playlist, error)
-	Intersect(token, firstPlaylist, secondPlaylist, name string) (*NewPlaylistResponse, error)
-	Union(token, firstPlaylist, secondPlaylist, name string) (*NewPlaylistResponse, error)
-	Complement(token, firstPlaylist, secondPlaylist, name string) (*NewPlaylistResponse, error)
+	UserPlaylists(ctx context.Context, token string, offset, limit int, username string) (*Playlists, error)
+	Profile(ctx context.Context, token string) (*User, error)
+	Playlists(ctx context.Context, token string, offset, limit int) (*Playlists, error)
+	Playlist(ctx context.Context, token, id string) (*Playlist, error)
+	Intersect(ctx context.Context, token string, playlists []string, name string, opts Options) (*NewPlaylistResponse, error)
+	Union(ctx context.Context, token string, playlists []string, name string, opts Options) (*NewPlaylistResponse, error)
+	Complement(ctx context.Context, token string, playlists []string, name string, opts Options) (*NewPlaylistResponse, error)
+	SymmetricDifference(ctx context.Context, token string, playlists []string, name string, opts Options) (*NewPlaylistResponse, error)
+	Evaluate(ctx context.Context, token, expr, name string, opts Options) (*NewPlaylistResponse, error)
+	Quorum(ctx context.Context, token string, playlists []string, k int, name string, opts Options) (*NewPlaylistResponse, error)
+	Compare(ctx context.Context, token, first, second string, opts Options) (*Comparison, error)
+	Similarity(ctx context.Context, token string, top int, metric string, opts Options) (*Similarity, error)
 }
 
 // Image specifies image urls of an object
@@ -62,6 +79,7 @@ type PlaylistDecoder struct {
 	Owner     Owner     `json:"owner"`
 	Public    bool      `json:"public"`
 	Tracks    Tracks    `json:"tracks"`
+	Snapshot  string    `json:"snapshot_id"`
 	Images    []Image   `json:"images"`
 	Followers Followers `json:"followers,omitempty"`
 }
@@ -99,6 +117,37 @@ type Playlist struct {
 	URI    string `json:"uri,omitempty"`
 	Image  string `json:"image,omitempty"`
 	Likes  int    `json:"likes,omitempty"`
+	// Snapshot is the version of the playlist
+	Snapshot string `json:"snapshot_id,omitempty"`
+	// Track specific information
+	Artists     []Artist     `json:"artists,omitempty"`
+	Album       *Album       `json:"album,omitempty"`
+	Popularity  int          `json:"popularity,omitempty"`
+	Duration    int          `json:"duration_ms,omitempty"`
+	Explicit    bool         `json:"explicit,omitempty"`
+	ExternalIDs *ExternalIDs `json:"external_ids,omitempty"`
+	// Sources is the number of playlists containing the track
+	Sources int `json:"sources,omitempty"`
+}
+
+// Album refers to the album a track belongs to
+type Album struct {
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name"`
+	URI         string `json:"uri,omitempty"`
+	ReleaseDate string `json:"release_date,omitempty"`
+}
+
+// Artist refers to the performer of a track
+type Artist struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+	URI  string `json:"uri,omitempty"`
+}
+
+// ExternalIDs contains the identifiers of a track outside of Spotify
+type ExternalIDs struct {
+	ISRC string `json:"isrc,omitempty"`
 }
 
 // User encodes/decodes the user id for Spotify
@@ -122,43 +171,69 @@ type Track struct {
 
 // NewPlaylistResponse is the response object when creating a new playlist
 type NewPlaylistResponse struct {
-	Name   string `json:"name"`
-	Href   string `json:"href"`
-	Tracks int    `json:"tracks"`
+	Name     string     `json:"name"`
+	Href     string     `json:"href"`
+	Tracks   int        `json:"tracks"`
+	Snapshot string     `json:"snapshot_id,omitempty"`
+	Items    []Playlist `json:"items,omitempty"`
+	// Groups are the artists or albums of the result when the operation doesn't compare tracks
+	Groups []Group `json:"groups,omitempty"`
+	// Filters tells how many tracks each filter removed from the result
+	Filters []FilterReport `json:"filters,omitempty"`
+}
+
+// Snapshot identifies a version of a playlist, it is returned every time its tracks are modified
+type Snapshot struct {
+	ID string `json:"snapshot_id"`
 }
 
-var httpClient *http.Client = &http.Client{}
-var data url.Values = url.Values{}
+var httpClient *http.Client = &http.Client{
+	Transport: newRetryTransport(http.DefaultTransport),
+}
 
 // Playlists retrieves the playlists from the user
-func (c Client) Playlists(token string, offset string) (*Playlists, error) {
-	return getPlaylists(token, offset, "me", c)
+func (c Client) Playlists(ctx context.Context, token string, offset, limit int) (*Playlists, error) {
+	return getPlaylists(ctx, token, offset, limit, "me", c)
 }
 
 // Playlist gets information regarding a specified playlist
-func (c Client) Playlist(token, id string) (*Playlist, error) {
-	return getPlaylist(token, id, c)
+func (c Client) Playlist(ctx context.Context, token, id string) (*Playlist, error) {
+	id, err := idOf("id", KindPlaylist, id)
+	if err != nil {
+		return nil, err
+	}
+	return getPlaylist(ctx, token, id, c)
 }
 
 // UserPlaylists retrieves the playlists from the user
-func (c Client) UserPlaylists(token, offset, username string) (*Playlists, error) {
-	username = fmt.Sprintf("users/%s", username)
-	return getPlaylists(token, offset, username, c)
+func (c Client) UserPlaylists(ctx context.Context, token string, offset, limit int, username string) (*Playlists, error) {
+	username, err := idOf("username", KindUser, username)
+	if err != nil {
+		return nil, err
+	}
+	return getPlaylists(ctx, token, offset, limit, fmt.Sprintf("users/%s", username), c)
 }
 
-func request(url, path, token string, dat interface{}) ([]byte, error) {
-	// The URL for the request
-	uri := fmt.Sprintf("%s/%s", url, path)
+func request(ctx context.Context, url, path, token string, dat interface{}) ([]byte, error) {
 	// Specify if the request its a GET or a POST
 	method := "GET"
-	var requestBody []byte
 	if dat != nil {
 		method = "POST"
+	}
+	return send(ctx, method, url, path, token, dat)
+}
+
+// send makes a request to Spotify with any HTTP method, the body is only sent when dat is not nil
+func send(ctx context.Context, method, url, path, token string, dat interface{}) ([]byte, error) {
+	// The URL for the request
+	uri := fmt.Sprintf("%s/%s", url, path)
+	var requestBody []byte
+	if dat != nil {
 		// Add body
 		requestBody, _ = json.Marshal(dat)
 	}
 	// Create the request object
-	req, err := http.NewRequest(method, uri, bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(ctx, method, uri, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return nil, err
 	}
@@ -180,32 +255,23 @@ func request(url, path, token string, dat interface{}) ([]byte, error) {
 
 	// Manage the response if it's not an OK Status
 	if res.StatusCode > 299 || res.StatusCode < 200 {
+		// Spotify describes the error in the body, use the status text when it doesn't
 		var errResponse transport.IntersectError
-		err = json.Unmarshal(body, &errResponse)
-
-		if err != nil {
-			return nil, err
-		}
-
-		errResponse = transport.IntersectError{
-			Error: errResponse.Error,
+		message := http.StatusText(res.StatusCode)
+		if json.Unmarshal(body, &errResponse) == nil && errResponse.Error.Message != "" {
+			message = errResponse.Error.Message
 		}
 
-		resp, err := json.Marshal(errResponse)
-		if err != nil {
-			return nil, err
-		}
-
-		return nil, fmt.Errorf("%s", resp)
+		return nil, transport.NewUpstreamError(res.StatusCode, message)
 	}
 
 	return body, nil
 
 }
 
-func userRequest(url, path, token string, dat interface{}) (*User, error) {
+func userRequest(ctx context.Context, url, path, token string, dat interface{}) (*User, error) {
 	// Make the request and get the response
-	body, err := request(url, path, token, dat)
+	body, err := request(ctx, url, path, token, dat)
 	if err != nil {
 		return nil, err
 	}
@@ -227,9 +293,25 @@ func userRequest(url, path, token string, dat interface{}) (*User, error) {
 	return &userResponse, nil
 }
 
-func playlistRequest(url, path, token string) (*Playlist, error) {
+// snapshotRequest modifies the tracks of a playlist and returns the new snapshot ID of the playlist
+func snapshotRequest(ctx context.Context, method, url, path, token string, dat interface{}) (string, error) {
+	body, err := send(ctx, method, url, path, token, dat)
+	if err != nil {
+		return "", err
+	}
+
+	var snapshot Snapshot
+	err = json.Unmarshal(body, &snapshot)
+	if err != nil {
+		return "", err
+	}
+
+	return snapshot.ID, nil
+}
+
+func playlistRequest(ctx context.Context, url, path, token string) (*Playlist, error) {
 	// Make the request and get the response
-	body, err := request(url, path, token, nil)
+	body, err := request(ctx, url, path, token, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -254,14 +336,16 @@ func playlistRequest(url, path, token string) (*Playlist, error) {
 		Image: image,
 		Owner: playlist.Owner.Name,
 		Likes: playlist.Followers.Total,
+		// The snapshot identifies the current version of the tracks of the playlist
+		Snapshot: playlist.Snapshot,
 	}
 
 	return &playlistResponse, nil
 }
 
-func getPlaylist(token, id string, c Client) (*Playlist, error) {
+func getPlaylist(ctx context.Context, token, id string, c Client) (*Playlist, error) {
 	url := fmt.Sprintf("v1/playlists/%s", id)
-	playlist, err := playlistRequest(c.URL, url, token)
+	playlist, err := playlistRequest(ctx, c.URL, url, token)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,81 @@
+package spotify
+
+import (
+	"context"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+// Modes to write the result of an operation into an existing playlist
+const (
+	// ModeReplace replaces all the tracks of the playlist with the result
+	ModeReplace = "replace"
+	// ModeAppend adds the result at the end of the playlist
+	ModeAppend = "append"
+	// ModeMerge adds the tracks of the result that are not already in the playlist
+	ModeMerge = "merge"
+	// ModeRemove removes the tracks of the result from the library, it is only available when the target is the library
+	ModeRemove = "remove"
+)
+
+// writePlaylist writes the tracks into the target playlist of the options following its mode
+// When there is nothing to add the playlist is left as it is and its current snapshot is returned.
+func writePlaylist(ctx context.Context, token string, tracks []Playlist, opts Options, c Client) (*NewPlaylistResponse, error) {
+	mode := opts.Mode
+	if mode == "" {
+		mode = ModeReplace
+	}
+
+	// An empty result would clear the playlist when replacing it, so it is rejected before anything is modified
+	if len(tracks) == 0 && mode == ModeReplace {
+		return nil, &transport.EmptyResultError{Message: "The result doesn't have any track to write into the playlist"}
+	}
+
+	key, err := opts.identity()
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the playlist exists before modifying it
+	playlist, err := getPlaylist(ctx, token, opts.Target, c)
+	if err != nil {
+		return nil, err
+	}
+
+	// Merging only adds the tracks that are not already in the playlist
+	if mode == ModeMerge {
+		existing, err := getTracks(ctx, token, opts.Target, c)
+		if err != nil {
+			return nil, err
+		}
+
+		lookup := setOf(*existing, key)
+		missing := []Playlist{}
+		for _, track := range tracks {
+			if !lookup.contains(track) {
+				missing = append(missing, track)
+			}
+		}
+		tracks = missing
+	}
+
+	response := NewPlaylistResponse{
+		Name:     playlist.Name,
+		Href:     opts.Target,
+		Snapshot: playlist.Snapshot,
+	}
+	if len(tracks) == 0 {
+		return &response, nil
+	}
+
+	// Replacing sets the tracks of the playlist with a PUT, appending and merging add them with a POST
+	uris := urisOf(tracks)
+	snapshot, written, err := addTracks(ctx, token, opts.Target, uris, mode == ModeReplace, c)
+	if err != nil {
+		return nil, partialError(err, written, len(uris), "the playlist was left with the tracks added so far")
+	}
+	response.Tracks = len(uris)
+	response.Snapshot = snapshot
+
+	return &response, nil
+}
@@ -0,0 +1,175 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jacobgarcia/settify/transport"
+)
+
+// fakeTarget serves an existing playlist with the given tracks and records every write made to it,
+// failing once failAfter chunks were written
+func fakeTarget(t *testing.T, existing PlaylistResponse, failAfter int, writes *[]string, written *[]string) *httptest.Server {
+	t.Helper()
+	chunks := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/playlists/target" && r.Method == "GET":
+			fmt.Fprint(w, `{"id": "target", "name": "Target", "snapshot_id": "current"}`)
+		case r.URL.Path == "/v1/playlists/target/tracks" && r.Method == "GET":
+			json.NewEncoder(w).Encode(existing)
+		case r.URL.Path == "/v1/playlists/target/tracks":
+			if chunks == failAfter {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error": {"status": 400, "message": "Bad request"}}`)
+				return
+			}
+			chunks++
+
+			var body map[string][]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if len(body["uris"]) > chunkSize {
+				t.Errorf("Expected at most %d tracks per request, Got %d", chunkSize, len(body["uris"]))
+			}
+			*writes = append(*writes, r.Method)
+			*written = append(*written, body["uris"]...)
+			fmt.Fprintf(w, `{"snapshot_id": "snapshot-%d"}`, chunks)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+}
+
+func TestWritePlaylistModes(t *testing.T) {
+	tracks := tracksOf(largePlaylist(0, 250))
+
+	cases := []struct {
+		mode     string
+		existing PlaylistResponse
+		writes   []string
+		written  int
+		snapshot string
+	}{
+		{ModeReplace, playlistOf(), []string{"PUT", "POST", "POST"}, 250, "snapshot-3"},
+		{"", playlistOf(), []string{"PUT", "POST", "POST"}, 250, "snapshot-3"},
+		{ModeAppend, largePlaylist(0, 10), []string{"POST", "POST", "POST"}, 250, "snapshot-3"},
+		{ModeMerge, largePlaylist(0, 200), []string{"POST"}, 50, "snapshot-1"},
+	}
+
+	for _, c := range cases {
+		writes := []string{}
+		written := []string{}
+		ts := fakeTarget(t, c.existing, -1, &writes, &written)
+
+		response, err := writePlaylist(context.Background(), "token", tracks, Options{Target: "target", Mode: c.mode}, Client{URL: ts.URL})
+		ts.Close()
+		if err != nil {
+			t.Errorf("Unexpected error with mode %q: %s", c.mode, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(writes, c.writes) {
+			t.Errorf("Expected %v with mode %q, Got %v", c.writes, c.mode, writes)
+		}
+		if len(written) != c.written || response.Tracks != c.written || response.Snapshot != c.snapshot {
+			t.Errorf("Expected %d tracks and %s with mode %q, Got %d, %d and %s", c.written, c.snapshot, c.mode, len(written), response.Tracks, response.Snapshot)
+		}
+		// The tracks keep the order of the result, merging skips the ones already in the playlist
+		first := tracks[len(tracks)-c.written].URI
+		if len(written) > 0 && written[0] != first {
+			t.Errorf("Expected %s first with mode %q, Got %s", first, c.mode, written[0])
+		}
+	}
+}
+
+func TestWritePlaylistMergeMatch(t *testing.T) {
+	existing := toPlaylistResponse([]Playlist{{ID: "old", URI: "spotify:track:old", ExternalIDs: &ExternalIDs{ISRC: "USRC1"}}})
+	tracks := []Playlist{
+		{ID: "new", URI: "spotify:track:new", ExternalIDs: &ExternalIDs{ISRC: "USRC1"}},
+		{ID: "other", URI: "spotify:track:other", ExternalIDs: &ExternalIDs{ISRC: "USRC2"}},
+	}
+
+	writes := []string{}
+	written := []string{}
+	ts := fakeTarget(t, existing, -1, &writes, &written)
+	defer ts.Close()
+
+	// The same recording under a different ID is already in the playlist when matching by ISRC
+	_, err := writePlaylist(context.Background(), "token", tracks, Options{Target: "target", Mode: ModeMerge, Match: "isrc"}, Client{URL: ts.URL})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if expected := []string{"spotify:track:other"}; !reflect.DeepEqual(written, expected) {
+		t.Errorf("Expected %v, Got %v", expected, written)
+	}
+}
+
+func TestWritePlaylistPartialFailure(t *testing.T) {
+	writes := []string{}
+	written := []string{}
+	ts := fakeTarget(t, playlistOf(), 1, &writes, &written)
+	defer ts.Close()
+
+	_, err := writePlaylist(context.Background(), "token", tracksOf(largePlaylist(0, 250)), Options{Target: "target"}, Client{URL: ts.URL})
+	var partial *transport.PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("Expected a partial error, Got %v", err)
+	}
+	if !strings.Contains(partial.Message, "Only 100 of 250 tracks were added") {
+		t.Errorf("Expected the error to report the added tracks, Got %s", partial.Message)
+	}
+}
+
+func TestWritePlaylistEmptyResult(t *testing.T) {
+	writes := []string{}
+	written := []string{}
+	ts := fakeTarget(t, largePlaylist(0, 10), -1, &writes, &written)
+	defer ts.Close()
+
+	// An empty result never clears the playlist
+	_, err := writePlaylist(context.Background(), "token", []Playlist{}, Options{Target: "target", Mode: ModeReplace}, Client{URL: ts.URL})
+	var empty *transport.EmptyResultError
+	if !errors.As(err, &empty) {
+		t.Errorf("Expected an empty result error, Got %v", err)
+	}
+	if len(writes) != 0 {
+		t.Errorf("Expected no writes, Got %v", writes)
+	}
+}
+
+func TestWritePlaylistNothingToAdd(t *testing.T) {
+	cases := []struct {
+		mode   string
+		tracks []Playlist
+	}{
+		{ModeMerge, tracksOf(largePlaylist(0, 10))},
+		{ModeAppend, []Playlist{}},
+	}
+
+	for _, c := range cases {
+		writes := []string{}
+		written := []string{}
+		ts := fakeTarget(t, largePlaylist(0, 10), -1, &writes, &written)
+
+		// The playlist is left as it is and its current snapshot is returned
+		response, err := writePlaylist(context.Background(), "token", c.tracks, Options{Target: "target", Mode: c.mode}, Client{URL: ts.URL})
+		ts.Close()
+		if err != nil {
+			t.Errorf("Unexpected error with mode %q: %s", c.mode, err)
+			continue
+		}
+		if len(writes) != 0 {
+			t.Errorf("Expected no writes with mode %q, Got %v", c.mode, writes)
+		}
+		if response.Tracks != 0 || response.Snapshot != "current" {
+			t.Errorf("Expected %d tracks and %s with mode %q, Got %d and %s", 0, "current", c.mode, response.Tracks, response.Snapshot)
+		}
+	}
+}
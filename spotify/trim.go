@@ -0,0 +1,59 @@
+package spotify
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// trim shortens the result of an operation once it was filtered and ordered.
+// First a random sample is taken, then tracks are added while they fit in the maximum duration and finally the limit is applied.
+// Every step keeps the order of the tracks.
+func trim(tracks []Playlist, opts Options) []Playlist {
+	if opts.Sample > 0 {
+		tracks = sample(tracks, opts.Sample, opts.Seed)
+	}
+	if opts.MaxDuration > 0 {
+		tracks = capDuration(tracks, opts.MaxDuration)
+	}
+	if opts.Limit > 0 && opts.Limit < len(tracks) {
+		tracks = tracks[:opts.Limit]
+	}
+	return tracks
+}
+
+// sample picks size random tracks, the same seed always picks the same tracks
+func sample(tracks []Playlist, size int, seed int64) []Playlist {
+	if size >= len(tracks) {
+		return tracks
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	random := rand.New(rand.NewSource(seed))
+	picked := random.Perm(len(tracks))[:size]
+	sort.Ints(picked)
+
+	sampled := make([]Playlist, 0, size)
+	for _, index := range picked {
+		sampled = append(sampled, tracks[index])
+	}
+	return sampled
+}
+
+// capDuration keeps the tracks that fit in the maximum duration using the duration_ms of each track.
+// A track that doesn't fit is skipped so shorter tracks after it can still be added.
+func capDuration(tracks []Playlist, max time.Duration) []Playlist {
+	capped := []Playlist{}
+	total := time.Duration(0)
+	for _, track := range tracks {
+		duration := time.Duration(track.Duration) * time.Millisecond
+		if total+duration > max {
+			continue
+		}
+		total += duration
+		capped = append(capped, track)
+	}
+	return capped
+}
@@ -0,0 +1,52 @@
+package spotify
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTrim(t *testing.T) {
+	tracks := []Playlist{
+		{ID: "1", Duration: 240000},
+		{ID: "2", Duration: 600000},
+		{ID: "3", Duration: 180000},
+		{ID: "4", Duration: 300000},
+		{ID: "5", Duration: 120000},
+	}
+
+	cases := []struct {
+		opts     Options
+		expected []string
+	}{
+		{Options{}, []string{"1", "2", "3", "4", "5"}},
+		{Options{Limit: 2}, []string{"1", "2"}},
+		{Options{Limit: 10}, []string{"1", "2", "3", "4", "5"}},
+		{Options{MaxDuration: 10 * time.Minute}, []string{"1", "3", "5"}},
+		{Options{MaxDuration: 10 * time.Minute, Limit: 2}, []string{"1", "3"}},
+		{Options{Sample: 10}, []string{"1", "2", "3", "4", "5"}},
+	}
+
+	for _, c := range cases {
+		if ids := idsOf(trim(tracks, c.opts)); !reflect.DeepEqual(ids, c.expected) {
+			t.Errorf("Expected %v, Got %v", c.expected, ids)
+		}
+	}
+}
+
+func TestSampleSeed(t *testing.T) {
+	tracks := tracksOf(largePlaylist(0, 100))
+
+	first := idsOf(trim(tracks, Options{Sample: 30, Seed: 42}))
+	second := idsOf(trim(tracks, Options{Sample: 30, Seed: 42}))
+	if len(first) != 30 || !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the same 30 tracks with the same seed, Got %v and %v", first, second)
+	}
+
+	// The sample keeps the order of the result
+	for i := 1; i < len(first); i++ {
+		if first[i-1] > first[i] {
+			t.Fatalf("Expected the tracks in order, Got %v", first)
+		}
+	}
+}
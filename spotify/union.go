@@ -1,20 +1,36 @@
 package spotify
 
-func unify(first PlaylistResponse, second PlaylistResponse) ([]Playlist, error) {
-	tracksUnion := append(first.Items, second.Items...)
-	union := []Playlist{}
-	for _, item := range tracksUnion {
-		playlist := Playlist{
-			ID:   item.Track.ID,
-			Name: item.Track.Name,
-			URI:  item.Track.URI,
+import "context"
+
+func unify(first PlaylistResponse, second PlaylistResponse, opts Options) ([]Playlist, error) {
+	key, err := opts.identity()
+	if err != nil {
+		return nil, err
+	}
+
+	// The result keeps the order in which tracks first appear.
+	// Distinct semantics keep every track once, bag semantics add or take the maximum of the counts.
+	left := setOf(first, key)
+	right := setOf(second, key)
+	union := newTrackSet(key)
+	for _, set := range []*trackSet{left, right} {
+		for _, k := range set.keys {
+			track := set.tracks[k]
+			if union.contains(track) {
+				continue
+			}
+			count := left.count(track) + right.count(track)
+			if opts.Semantics == SemanticsBagMax {
+				count = maximum(left.count(track), right.count(track))
+			}
+			union.add(track, opts.multiplicity(count))
 		}
-		union = append(union, playlist)
 	}
-	return union, nil
+
+	return union.items(), nil
 }
 
-// Union merges two playlist tracks into one
-func (c Client) Union(token, firstPlaylist, secondPlaylist, name string) (*NewPlaylistResponse, error) {
-	return operation(token, firstPlaylist, secondPlaylist, name, c, unify)
+// Union merges the tracks of a list of playlists into one
+func (c Client) Union(ctx context.Context, token string, playlists []string, name string, opts Options) (*NewPlaylistResponse, error) {
+	return operation(ctx, token, playlists, name, opts, c, fold(unify))
 }
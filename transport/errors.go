@@ -0,0 +1,186 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// UpstreamError is an error returned by the Spotify API
+type UpstreamError struct {
+	Status  int
+	Message string
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("spotify responded %d: %s", e.Status, e.Message)
+}
+
+// AuthError means the request doesn't have valid credentials
+type AuthError struct {
+	Message string
+	// Err is the error returned by Spotify, if it rejected the credentials
+	Err error
+}
+
+func (e *AuthError) Error() string {
+	return e.Message
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError means a parameter of the request is not valid
+type ValidationError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// NotFoundError means a resource requested doesn't exist
+type NotFoundError struct {
+	Message string
+	// Err is the error returned by Spotify, if it couldn't find the resource
+	Err error
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Message
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// EmptyResultError means an operation didn't produce anything to save
+type EmptyResultError struct {
+	Message string
+}
+
+func (e *EmptyResultError) Error() string {
+	return e.Message
+}
+
+// TooLargeError means a listing of Spotify has more items than the service retrieves,
+// it is reported instead of computing a result with only part of the listing
+type TooLargeError struct {
+	Listing string
+	Total   int
+	Limit   int
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("The listing %s has %d items but only %d can be retrieved", e.Listing, e.Total, e.Limit)
+}
+
+// PartialError means an operation failed after some of its changes were already made
+type PartialError struct {
+	Message string
+	// Err is the error that stopped the operation
+	Err error
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Message, e.Err)
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+// NewUpstreamError classifies an error response from Spotify
+func NewUpstreamError(status int, message string) error {
+	upstream := &UpstreamError{Status: status, Message: message}
+	switch status {
+	case http.StatusUnauthorized:
+		return &AuthError{Message: message, Err: upstream}
+	case http.StatusNotFound:
+		return &NotFoundError{Message: message, Err: upstream}
+	}
+	return upstream
+}
+
+// StatusOf maps an error to the HTTP status code of the response
+func StatusOf(err error) int {
+	return kindOf(err).status
+}
+
+// statusClientClosedRequest is the status of a request cancelled by the client before the response was sent,
+// the client never sees it but it keeps cancellations apart from failures of the service
+const statusClientClosedRequest = 499
+
+// kind describes a class of errors in the responses of the API
+type kind struct {
+	status int
+	// problem is the type of the problem details, relative to the problems path
+	problem string
+	title   string
+}
+
+// kindOf classifies an error
+func kindOf(err error) kind {
+	var upstream *UpstreamError
+	var auth *AuthError
+	var validation *ValidationError
+	var validations ValidationErrors
+	var notFound *NotFoundError
+	var empty *EmptyResultError
+	var partial *PartialError
+	var tooLarge *TooLargeError
+
+	switch {
+	case errors.As(err, &validation), errors.As(err, &validations):
+		return kind{http.StatusBadRequest, "validation-error", "The request is not valid"}
+	case errors.As(err, &auth):
+		return kind{http.StatusUnauthorized, "auth-error", "The request is not authorized"}
+	case errors.As(err, &notFound):
+		return kind{http.StatusNotFound, "not-found", "The resource was not found"}
+	case errors.As(err, &empty):
+		return kind{http.StatusUnprocessableEntity, "empty-result", "The operation has no tracks"}
+	case errors.As(err, &tooLarge):
+		return kind{http.StatusUnprocessableEntity, "too-large", "A listing has more items than can be retrieved"}
+	case errors.Is(err, context.DeadlineExceeded):
+		return kind{http.StatusGatewayTimeout, "timeout", "Spotify took too long to respond"}
+	case errors.Is(err, context.Canceled):
+		return kind{statusClientClosedRequest, "cancelled", "The request was cancelled by the client"}
+	case errors.As(err, &upstream):
+		// Client errors are passed through, failures of Spotify are reported as a bad gateway
+		status := http.StatusBadGateway
+		if upstream.Status >= 400 && upstream.Status < 500 {
+			status = upstream.Status
+		}
+		if errors.As(err, &partial) {
+			return kind{status, "partial-failure", "The operation was only partially completed"}
+		}
+		return kind{status, "upstream-error", "Spotify failed to complete the request"}
+	}
+
+	return kind{http.StatusInternalServerError, "", http.StatusText(http.StatusInternalServerError)}
+}
+
+// messageOf gets the message shown to the client for an error, internal errors are not exposed
+func messageOf(err error, status int) string {
+	var partial *PartialError
+	var upstream *UpstreamError
+	switch {
+	case errors.As(err, &partial):
+		return partial.Message + ": " + messageOf(partial.Err, status)
+	case errors.As(err, &upstream):
+		return upstream.Message
+	case status == http.StatusInternalServerError:
+		return http.StatusText(status)
+	case status == http.StatusGatewayTimeout:
+		return "The request was cancelled before Spotify responded"
+	case status == statusClientClosedRequest:
+		return "The client closed the request before Spotify responded"
+	}
+	return err.Error()
+}
@@ -0,0 +1,33 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestStatusOf(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{&ValidationError{Field: "k", Message: "must be a number"}, 400},
+		{&AuthError{Message: "Bearer TOKEN is missing"}, 401},
+		{NewUpstreamError(401, "The access token expired"), 401},
+		{NewUpstreamError(404, "Not found"), 404},
+		{NewUpstreamError(429, "API rate limit exceeded"), 429},
+		{NewUpstreamError(503, "Service unavailable"), 502},
+		{&EmptyResultError{Message: "Playlists doesn't have anything in common"}, 422},
+		{&TooLargeError{Listing: "v1/me/tracks", Total: 12000, Limit: 10000}, 422},
+		{&PartialError{Message: "Only 100 of 200 tracks were added", Err: NewUpstreamError(500, "Server error")}, 502},
+		{fmt.Errorf("fetching tracks: %w", context.DeadlineExceeded), 504},
+		{fmt.Errorf("fetching tracks: %w", context.Canceled), 499},
+		{fmt.Errorf("unexpected end of JSON input"), 500},
+	}
+
+	for _, c := range cases {
+		if status := StatusOf(c.err); status != c.status {
+			t.Errorf("%s: Expected %d, Got %d", c.err, c.status, status)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ProblemsPath is where the types of the problem details are documented
+const ProblemsPath = "/problems/"
+
+// Problem is the body of every error response, following RFC 7807
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// UpstreamStatus is the status code Spotify responded with, when the error comes from Spotify
+	UpstreamStatus int `json:"upstreamStatus,omitempty"`
+	// RequestID identifies the request in the logs
+	RequestID string `json:"requestId,omitempty"`
+	// Errors lists the invalid parameters of the request
+	Errors []*ValidationError `json:"errors,omitempty"`
+}
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	instanceKey
+)
+
+// PopulateRequestContext stores the request ID and the path of the request in the context, so errors can reference them.
+// The request ID is taken from the X-Request-ID header or generated when missing.
+func PopulateRequestContext(ctx context.Context, req *http.Request) context.Context {
+	id := req.Header.Get("X-Request-ID")
+	if id == "" {
+		id = newRequestID()
+	}
+
+	ctx = context.WithValue(ctx, requestIDKey, id)
+	return context.WithValue(ctx, instanceKey, req.URL.RequestURI())
+}
+
+// RequestID gets the ID of the request stored in the context
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// SetRequestIDHeader returns the ID of the request in the X-Request-ID header
+func SetRequestIDHeader(ctx context.Context, w http.ResponseWriter) context.Context {
+	if id := RequestID(ctx); id != "" {
+		w.Header().Set("X-Request-ID", id)
+	}
+	return ctx
+}
+
+func newRequestID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
+
+// ProblemOf creates the problem details of an error
+func ProblemOf(ctx context.Context, err error) Problem {
+	k := kindOf(err)
+
+	problem := Problem{
+		Type:   "about:blank",
+		Title:  k.title,
+		Status: k.status,
+		Detail: messageOf(err, k.status),
+	}
+	if k.problem != "" {
+		problem.Type = ProblemsPath + k.problem
+	}
+
+	var upstream *UpstreamError
+	if errors.As(err, &upstream) {
+		problem.UpstreamStatus = upstream.Status
+	}
+
+	var validation *ValidationError
+	var validations ValidationErrors
+	if errors.As(err, &validations) {
+		problem.Errors = validations
+		problem.Detail = "Some parameters of the request are not valid"
+	} else if errors.As(err, &validation) {
+		problem.Errors = []*ValidationError{validation}
+	}
+
+	problem.RequestID = RequestID(ctx)
+	problem.Instance, _ = ctx.Value(instanceKey).(string)
+
+	return problem
+}
+
+// ErrorEncoder returns an application/problem+json response for errors
+func ErrorEncoder(ctx context.Context, err error, w http.ResponseWriter) {
+	if err == nil {
+		panic("encodeError with nil error")
+	}
+
+	problem := ProblemOf(ctx, err)
+
+	w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+	SetRequestIDHeader(ctx, w)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
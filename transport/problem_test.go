@@ -0,0 +1,42 @@
+package transport
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestErrorEncoder(t *testing.T) {
+	req := httptest.NewRequest("GET", "/intersection?firstPlaylist=a", nil)
+	req.Header.Set("X-Request-ID", "request")
+	ctx := PopulateRequestContext(req.Context(), req)
+
+	w := httptest.NewRecorder()
+	ErrorEncoder(ctx, NewUpstreamError(503, "Service unavailable"), w)
+
+	if w.Code != 502 {
+		t.Errorf("Expected %d, Got %d", 502, w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/problem+json; charset=utf-8" {
+		t.Errorf("Expected a problem+json response, Got %s", contentType)
+	}
+
+	var problem Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := Problem{
+		Type:           ProblemsPath + "upstream-error",
+		Title:          "Spotify failed to complete the request",
+		Status:         502,
+		Detail:         "Service unavailable",
+		Instance:       "/intersection?firstPlaylist=a",
+		UpstreamStatus: 503,
+		RequestID:      "request",
+	}
+	if !reflect.DeepEqual(problem, expected) {
+		t.Errorf("Expected %+v, Got %+v", expected, problem)
+	}
+}
@@ -3,131 +3,322 @@ package transport
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
 // AuthRequest is an authenticated request containing token
 type AuthRequest struct {
-	Token          string
-	FirstPlaylist  string
-	SecondPlaylist string
-	Offset         string
-	Name           string
-	Username       string
-	PlaylistID     string
+	Token string
+}
+
+// PlaylistsRequest lists the playlists of the current user
+type PlaylistsRequest struct {
+	AuthRequest
+	Offset int
+	// Limit is the maximum number of playlists listed, zero lists all of them
+	Limit int
+}
+
+// UserPlaylistsRequest lists the playlists of a user
+type UserPlaylistsRequest struct {
+	PlaylistsRequest
+	Username string
+}
+
+// PlaylistRequest gets the information of a playlist
+type PlaylistRequest struct {
+	AuthRequest
+	PlaylistID string
+}
+
+// OperationOptions are the parameters shared by every set operation
+type OperationOptions struct {
+	Name        string
+	Match       string
+	Order       string
+	Seed        int64
+	Semantics   string
+	DryRun      bool
+	Target      string
+	Mode        string
+	Filters     []string
+	Limit       int
+	Sample      int
+	MaxDuration time.Duration
+	Granularity string
+}
+
+// OperationRequest applies a set operation over a list of playlists
+type OperationRequest struct {
+	AuthRequest
+	OperationOptions
+	Playlists []string
+}
+
+// QuorumRequest gets the tracks in at least K of the playlists
+type QuorumRequest struct {
+	OperationRequest
+	K int
+}
+
+// EvaluateRequest evaluates a set expression
+type EvaluateRequest struct {
+	AuthRequest
+	OperationOptions
+	Expression string
+}
+
+// CompareRequest compares two playlists
+type CompareRequest struct {
+	AuthRequest
+	First  string
+	Second string
+	Match  string
+}
+
+// SimilarityRequest compares every playlist of the current user with each other
+type SimilarityRequest struct {
+	AuthRequest
+	Top    int
+	Metric string
+	Match  string
 }
 
 // DecodeAuthRequest serves as a middleware function to intercept requests in order to get the Authorization Bearer Token
 func DecodeAuthRequest(ctx context.Context, req *http.Request) (interface{}, error) {
-	token := req.Header.Get("Authorization")
-
-	offset := req.URL.Query().Get("offset")
-	firstPlaylist := req.URL.Query().Get("firstPlaylist")
-	secondPlaylist := req.URL.Query().Get("secondPlaylist")
-	name := req.URL.Query().Get("name")
-	username := req.URL.Query().Get("username")
+	return decodeAuth(req)
+}
 
-	vars := mux.Vars(req)
-	id := vars["id"]
+// DecodePlaylistsRequest decodes and validates a request listing the playlists of the current user
+func DecodePlaylistsRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	auth, err := decodeAuth(req)
+	if err != nil {
+		return nil, err
+	}
 
-	if token == "" {
-		fmt.Println("Token is missing")
-		var errResponse IntersectError
-		var nestedError NestedError
+	v := validator{query: req.URL.Query()}
+	s := PlaylistsRequest{
+		AuthRequest: auth,
+		Offset:      v.integer("offset", 0, maxOffset),
+		Limit:       v.integer("limit", 1, maxLimit),
+	}
 
-		nestedError = NestedError{
-			Message: "Bearer TOKEN is missing",
-			Status:  401,
-		}
+	return s, v.err()
+}
 
-		errResponse = IntersectError{
-			Error: nestedError,
-		}
+// DecodeUserPlaylistsRequest decodes and validates a request listing the playlists of a user
+func DecodeUserPlaylistsRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	auth, err := decodeAuth(req)
+	if err != nil {
+		return nil, err
+	}
 
-		resp, err := json.Marshal(errResponse)
+	v := validator{query: req.URL.Query()}
+	s := UserPlaylistsRequest{
+		PlaylistsRequest: PlaylistsRequest{
+			AuthRequest: auth,
+			Offset:      v.integer("offset", 0, maxOffset),
+			Limit:       v.integer("limit", 1, maxLimit),
+		},
+		Username: v.required("username"),
+	}
 
-		if err != nil {
-			return nil, err
-		}
+	return s, v.err()
+}
 
-		return nil, fmt.Errorf("%s", resp)
+// DecodePlaylistRequest decodes a request getting a playlist, its ID is normalized by the service
+func DecodePlaylistRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	auth, err := decodeAuth(req)
+	if err != nil {
+		return nil, err
 	}
 
-	s := AuthRequest{
-		Token:          token,
-		FirstPlaylist:  firstPlaylist,
-		SecondPlaylist: secondPlaylist,
-		Offset:         offset,
-		Name:           name,
-		Username:       username,
-		PlaylistID:     id,
+	s := PlaylistRequest{
+		AuthRequest: auth,
+		PlaylistID:  mux.Vars(req)["id"],
 	}
 
 	return s, nil
 }
 
-// EncodeResponse creates the standard response object
-func EncodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
-	w.Header().Set("Content-Type", "application/json;charset=utf-8")
-	return json.NewEncoder(w).Encode(response)
+// DecodeOperationRequest decodes and validates a set operation request
+func DecodeOperationRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	auth, err := decodeAuth(req)
+	if err != nil {
+		return nil, err
+	}
+
+	v := validator{query: req.URL.Query()}
+	s := decodeOperation(&v, auth, req)
+
+	return s, v.err()
 }
 
-// ErrorResponse is the standard response message for error handling
-type ErrorResponse struct {
-	Message string `json:"error"`
-	Status  int    `json:"status,omitempty"`
+// DecodeQuorumRequest decodes and validates a quorum request
+func DecodeQuorumRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	auth, err := decodeAuth(req)
+	if err != nil {
+		return nil, err
+	}
+
+	v := validator{query: req.URL.Query()}
+	operation := decodeOperation(&v, auth, req)
+	v.required("k")
+	s := QuorumRequest{
+		OperationRequest: operation,
+		K:                v.integer("k", 1, maxInt(len(operation.Playlists), 1)),
+	}
+
+	return s, v.err()
 }
 
-// NestedError is the nested response message for error handling
-type NestedError struct {
-	Message string `json:"message"`
-	Status  int    `json:"status,omitempty"`
+// DecodeEvaluateRequest decodes and validates a request evaluating a set expression
+func DecodeEvaluateRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	auth, err := decodeAuth(req)
+	if err != nil {
+		return nil, err
+	}
+
+	v := validator{query: req.URL.Query()}
+	s := EvaluateRequest{
+		AuthRequest:      auth,
+		OperationOptions: decodeOptions(&v),
+		Expression:       v.required("expr"),
+	}
+	v.length("expr", s.Expression, maxExpressionLength)
+
+	return s, v.err()
 }
 
-// IntersectError is the standard response message for error handling
-type IntersectError struct {
-	Error NestedError `json:"error"`
+// DecodeCompareRequest decodes and validates a request comparing two playlists
+func DecodeCompareRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	auth, err := decodeAuth(req)
+	if err != nil {
+		return nil, err
+	}
+
+	v := validator{query: req.URL.Query()}
+	s := CompareRequest{
+		AuthRequest: auth,
+		Match:       v.query.Get("match"),
+	}
+
+	playlists := decodePlaylists(req)
+	if len(playlists) != 2 {
+		v.add("playlists", "exactly two playlists are required")
+	} else {
+		s.First, s.Second = playlists[0], playlists[1]
+	}
+
+	return s, v.err()
 }
 
-// ErrorEncoder returns a REST API response for errors
-func ErrorEncoder(c context.Context, err error, w http.ResponseWriter) {
-	if err == nil {
-		panic("encodeError with nil error")
+// DecodeSimilarityRequest decodes and validates a request comparing the playlists of the current user
+func DecodeSimilarityRequest(ctx context.Context, req *http.Request) (interface{}, error) {
+	auth, err := decodeAuth(req)
+	if err != nil {
+		return nil, err
 	}
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
-	var errResponse ErrorResponse
+	v := validator{query: req.URL.Query()}
+	s := SimilarityRequest{
+		AuthRequest: auth,
+		Top:         v.integer("top", 1, maxPairs),
+		Metric:      v.query.Get("metric"),
+		Match:       v.query.Get("match"),
+	}
 
-	err = json.Unmarshal([]byte(err.Error()), &errResponse)
+	return s, v.err()
+}
 
-	msg := ErrorResponse{
-		Message: errResponse.Message,
+// decodeAuth gets the Authorization Bearer Token of the request
+func decodeAuth(req *http.Request) (AuthRequest, error) {
+	token := req.Header.Get("Authorization")
+	if token == "" {
+		return AuthRequest{}, &AuthError{Message: "Bearer TOKEN is missing"}
 	}
 
-	w.WriteHeader(errResponse.Status)
-	json.NewEncoder(w).Encode(msg)
+	return AuthRequest{Token: token}, nil
 }
 
-// IntersectErrorEncoder returns a REST API response for errors
-func IntersectErrorEncoder(c context.Context, err error, w http.ResponseWriter) {
-	if err == nil {
-		panic("encodeError with nil error")
+func decodeOperation(v *validator, auth AuthRequest, req *http.Request) OperationRequest {
+	playlists := decodePlaylists(req)
+	if len(playlists) < 2 {
+		v.add("playlists", "at least two playlists are required")
+	}
+	if len(playlists) > maxPlaylists {
+		v.add("playlists", "no more than %d playlists are allowed", maxPlaylists)
 	}
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	var errResponse IntersectError
 
-	err = json.Unmarshal([]byte(err.Error()), &errResponse)
-	if err != nil {
-		fmt.Println(err)
-		return
+	return OperationRequest{
+		AuthRequest:      auth,
+		OperationOptions: decodeOptions(v),
+		Playlists:        playlists,
 	}
-	msg := ErrorResponse{
-		Message: errResponse.Error.Message,
+}
+
+func decodeOptions(v *validator) OperationOptions {
+	options := OperationOptions{
+		Name:        v.query.Get("name"),
+		Match:       v.query.Get("match"),
+		Order:       v.query.Get("order"),
+		Seed:        v.int64("seed"),
+		Semantics:   v.query.Get("semantics"),
+		DryRun:      v.boolean("dryRun"),
+		Target:      v.query.Get("target"),
+		Mode:        v.query.Get("mode"),
+		Filters:     v.list("filter"),
+		Limit:       v.integer("limit", 1, maxTracks),
+		Sample:      v.integer("sample", 1, maxTracks),
+		MaxDuration: v.duration("maxDuration"),
+		Granularity: v.query.Get("granularity"),
 	}
+	v.length("name", options.Name, maxNameLength)
+	if len(options.Filters) > maxFilters {
+		v.add("filter", "no more than %d filters are allowed", maxFilters)
+	}
+
+	return options
+}
+
+// decodePlaylists gets the playlists of a set operation.
+// They can be sent as firstPlaylist and secondPlaylist, or as a list using repeated or comma separated playlists params.
+// Besides playlists, any source of tracks understood by the service like "saved" or "album:ID" can be sent.
+func decodePlaylists(req *http.Request) []string {
+	query := req.URL.Query()
+	values := []string{query.Get("firstPlaylist"), query.Get("secondPlaylist")}
+	for _, value := range query["playlists"] {
+		values = append(values, strings.Split(value, ",")...)
+	}
+
+	playlists := []string{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			playlists = append(playlists, value)
+		}
+	}
+
+	return playlists
+}
+
+// EncodeResponse creates the standard response object
+func EncodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	return json.NewEncoder(w).Encode(response)
+}
+
+// NestedError is the nested response message for error handling
+type NestedError struct {
+	Message string `json:"message"`
+	Status  int    `json:"status,omitempty"`
+}
 
-	w.WriteHeader(errResponse.Error.Status)
-	json.NewEncoder(w).Encode(msg)
+// IntersectError is the error object returned by Spotify
+type IntersectError struct {
+	Error NestedError `json:"error"`
 }
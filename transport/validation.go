@@ -0,0 +1,150 @@
+package transport
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits of the parameters of the requests
+const (
+	maxOffset           = 100000
+	maxLimit            = 1000
+	maxPlaylists        = 50
+	maxNameLength       = 100
+	maxExpressionLength = 2000
+	maxFilters          = 20
+	maxTracks           = 10000
+	maxPairs            = 1000
+)
+
+// ValidationErrors groups every invalid parameter of a request
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := []string{}
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// validator collects the errors found while decoding the parameters of a request
+type validator struct {
+	query  url.Values
+	errors ValidationErrors
+}
+
+// add reports an invalid field
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.errors = append(v.errors, &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns the errors found, or nil if the request is valid
+func (v *validator) err() error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return v.errors
+}
+
+// required gets a parameter that can't be empty
+func (v *validator) required(field string) string {
+	value := strings.TrimSpace(v.query.Get(field))
+	if value == "" {
+		v.add(field, "is required")
+	}
+	return value
+}
+
+// integer gets an optional number between min and max, returning zero when it's missing
+func (v *validator) integer(field string, min, max int) int {
+	value := v.query.Get(field)
+	if value == "" {
+		return 0
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		v.add(field, "must be a number")
+		return 0
+	}
+	if number < min || number > max {
+		v.add(field, "must be between %d and %d", min, max)
+	}
+	return number
+}
+
+// int64 gets an optional 64 bits number, returning zero when it's missing
+func (v *validator) int64(field string) int64 {
+	value := v.query.Get(field)
+	if value == "" {
+		return 0
+	}
+
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		v.add(field, "must be a number between %d and %d", math.MinInt64, math.MaxInt64)
+	}
+	return number
+}
+
+// boolean gets an optional flag, returning false when it's missing
+func (v *validator) boolean(field string) bool {
+	value := v.query.Get(field)
+	if value == "" {
+		return false
+	}
+
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		v.add(field, "must be true or false")
+	}
+	return flag
+}
+
+// duration gets an optional positive duration such as 90m or 1h30m, returning zero when it's missing
+func (v *validator) duration(field string) time.Duration {
+	value := v.query.Get(field)
+	if value == "" {
+		return 0
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		v.add(field, "must be a positive duration like 90m or 1h30m")
+		return 0
+	}
+	return duration
+}
+
+// list gets the values of a parameter that can be repeated or separated by commas, skipping the empty ones
+func (v *validator) list(field string) []string {
+	values := []string{}
+	for _, value := range v.query[field] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+// length checks the value doesn't have more than max characters
+func (v *validator) length(field, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		v.add(field, "must have at most %d characters", max)
+	}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,67 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeOperationRequest(t *testing.T) {
+	req := httptest.NewRequest("GET", "/intersection?playlists=a,b&playlists=c&name=Shared&dryRun=true&seed=7", nil)
+	req.Header.Set("Authorization", "Bearer token")
+
+	request, err := DecodeOperationRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	operation := request.(OperationRequest)
+	if !reflect.DeepEqual(operation.Playlists, []string{"a", "b", "c"}) {
+		t.Errorf("Expected %v, Got %v", []string{"a", "b", "c"}, operation.Playlists)
+	}
+	if operation.Name != "Shared" || !operation.DryRun || operation.Seed != 7 {
+		t.Errorf("Unexpected options %+v", operation.OperationOptions)
+	}
+}
+
+func TestDecodeRequestValidation(t *testing.T) {
+	cases := []struct {
+		url    string
+		decode func(context.Context, *http.Request) (interface{}, error)
+		fields []string
+	}{
+		{"/intersection?firstPlaylist=a", DecodeOperationRequest, []string{"playlists"}},
+		{"/intersection?playlists=a&seed=x&dryRun=maybe", DecodeOperationRequest, []string{"playlists", "seed", "dryRun"}},
+		{"/intersection?playlists=a,b&name=" + strings.Repeat("a", maxNameLength+1), DecodeOperationRequest, []string{"name"}},
+		{"/intersection?playlists=a,b&limit=0&sample=x&maxDuration=fast", DecodeOperationRequest, []string{"limit", "sample", "maxDuration"}},
+		{"/quorum?playlists=a,b,c&k=4", DecodeQuorumRequest, []string{"k"}},
+		{"/quorum?playlists=a,b,c", DecodeQuorumRequest, []string{"k"}},
+		{"/evaluate", DecodeEvaluateRequest, []string{"expr"}},
+		{"/playlists?offset=-1&limit=x", DecodePlaylistsRequest, []string{"offset", "limit"}},
+		{"/user/playlists", DecodeUserPlaylistsRequest, []string{"username"}},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", c.url, nil)
+		req.Header.Set("Authorization", "Bearer token")
+
+		_, err := c.decode(context.Background(), req)
+		var validations ValidationErrors
+		if !errors.As(err, &validations) {
+			t.Errorf("%s: Expected validation errors, Got %v", c.url, err)
+			continue
+		}
+
+		fields := []string{}
+		for _, validation := range validations {
+			fields = append(fields, validation.Field)
+		}
+		if !reflect.DeepEqual(fields, c.fields) {
+			t.Errorf("%s: Expected errors in %v, Got %v", c.url, c.fields, fields)
+		}
+	}
+}